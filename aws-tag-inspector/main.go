@@ -12,6 +12,7 @@ package main
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 
 	"github.com/Excoriate/daggerverse/aws-tag-inspector/internal/dagger"
@@ -34,9 +35,39 @@ type AwsTagInspector struct {
 	// Cfg is the configuration file to use for the container.
 	// +private
 	Cfg *inspectorConfig
-	// AWSClient is the AWS client to use for the container.
+	// AWSRegion is the region of the AWS client.
 	// +private
-	AWSClient *AWSClient
+	AWSRegion string
+	// AWSEndpoint is a custom endpoint for the AWS APIs.
+	// +private
+	AWSEndpoint string
+	// Regions are the regions to scan. If empty, the region of the AWS client is scanned.
+	// +private
+	Regions []string
+	// AWSAccessKeyID is the AWS access key ID of the static keys.
+	// +private
+	AWSAccessKeyID *dagger.Secret
+	// AWSSecretAccessKey is the AWS secret access key of the static keys.
+	// +private
+	AWSSecretAccessKey *dagger.Secret
+	// AWSSessionToken is the AWS session token of temporary credentials.
+	// +private
+	AWSSessionToken *dagger.Secret
+	// AWSConfigFile is the shared AWS config file.
+	// +private
+	AWSConfigFile *dagger.File
+	// AWSCredentialsFile is the shared AWS credentials file.
+	// +private
+	AWSCredentialsFile *dagger.File
+	// AWSProfile is the profile of the shared config and credentials files.
+	// +private
+	AWSProfile string
+	// AWSWebIdentityToken is the web identity token used with AWSRoleARN.
+	// +private
+	AWSWebIdentityToken *dagger.Secret
+	// AWSRoleARN is the ARN of the role assumed with the web identity token.
+	// +private
+	AWSRoleARN string
 	// Ctr is the main container to use for the module.
 	// +private
 	Ctr *dagger.Container
@@ -44,44 +75,96 @@ type AwsTagInspector struct {
 
 // New creates a new AwsTagInspector module.
 //
+// Exactly one source of AWS credentials must be passed: the static keys, the shared config and/or
+// credentials files with a profile, or a web identity token with a role ARN.
+//
 // Parameters:
-// - version: The version of the GoReleaser to use, e.g., "v1.22.0". Optional parameter.
-// - image: The image to use as the base container. Optional parameter.
-// - ctr: The container to use as a base container. Optional parameter.
+// - awsAccessKeyID: The AWS access key ID, used with awsSecretAccessKey. Optional parameter.
+// - awsSecretAccessKey: The AWS secret access key, used with awsAccessKeyID. Optional parameter.
+// - awsSessionToken: The session token of temporary credentials, used with the static keys. Optional parameter.
+// - awsConfigFile: A shared AWS config file, e.g. ~/.aws/config. Optional parameter.
+// - awsCredentialsFile: A shared AWS credentials file, e.g. ~/.aws/credentials. Optional parameter.
+// - awsProfile: The profile of the shared files. Default is "default". Optional parameter.
+// - awsWebIdentityToken: The web identity token, e.g. the OIDC token of a CI runner. Optional parameter.
+// - awsRoleArn: The ARN of the role assumed with the web identity token. Optional parameter.
+// - config: The tag compliance configuration file. Optional parameter.
+// - awsRegion: The AWS region to use. Default is "us-east-1". Optional parameter.
+// - awsRegions: The regions to scan concurrently, or "all" for every enabled region. Optional parameter.
+// - awsEndpoint: A custom endpoint for the AWS APIs, e.g. LocalStack or moto. Optional parameter.
 // - envVarsFromHost: A list of environment variables to pass from the host to the container in a
 // slice of strings. Optional parameter.
 //
-// Returns a pointer to a AwsTagInspector instance and an error, if any.
+// Returns a pointer to a AwsTagInspector instance and an error, if the credentials or the
+// configuration are invalid.
 func New(
 	// ctx is the context for the new function
 	// +optional
 	ctx context.Context,
 	// awsAccessKeyID is the AWS access key ID to use for the container.
+	// +optional
 	awsAccessKeyID *dagger.Secret,
 	// awsSecretAccessKey is the AWS secret access key to use for the container.
+	// +optional
 	awsSecretAccessKey *dagger.Secret,
+	// awsSessionToken is the AWS session token of temporary credentials, used with the access keys.
+	// +optional
+	awsSessionToken *dagger.Secret,
+	// awsConfigFile is a shared AWS config file (e.g. ~/.aws/config) with the profile to use.
+	// +optional
+	awsConfigFile *dagger.File,
+	// awsCredentialsFile is a shared AWS credentials file (e.g. ~/.aws/credentials) with the profile to use.
+	// +optional
+	awsCredentialsFile *dagger.File,
+	// awsProfile is the profile of the shared config and credentials files to use. Default is "default".
+	// +optional
+	awsProfile string,
+	// awsWebIdentityToken is the web identity token (e.g. the OIDC token of a CI runner), passed as
+	// a secret, e.g. file:/var/run/secrets/token. It's used with awsRoleArn.
+	// +optional
+	awsWebIdentityToken *dagger.Secret,
+	// awsRoleArn is the ARN of the role assumed with the web identity token.
+	// +optional
+	awsRoleArn string,
 	// configPath is the path to the configuration file to use for the container.
 	// +optional
 	config *dagger.File,
 	// awsRegion is the AWS region to use for the container.
 	// +optional
 	awsRegion string,
+	// awsRegions are the regions to scan, concurrently. Use "all" to scan every region enabled in the
+	// account. If not set, only awsRegion is scanned.
+	// +optional
+	awsRegions []string,
+	// awsEndpoint is a custom endpoint for the AWS APIs, e.g., a local AWS stand-in such as
+	// LocalStack or moto ("http://localhost:4566").
+	// +optional
+	awsEndpoint string,
 	// envVarsFromHost is a list of environment variables to pass from the host to the container in a slice of strings.
 	// +optional
 	envVarsFromHost []string,
 ) (*AwsTagInspector, error) {
 	//nolint:exhaustruct // It's 'okaysh' for now, I'll decide later what's going to be the pattern here.
 	dagModule := &AwsTagInspector{
-		Cfg: &inspectorConfig{},
+		Cfg:                 &inspectorConfig{},
+		AWSRegion:           awsRegion,
+		AWSEndpoint:         awsEndpoint,
+		Regions:             awsRegions,
+		AWSAccessKeyID:      awsAccessKeyID,
+		AWSSecretAccessKey:  awsSecretAccessKey,
+		AWSSessionToken:     awsSessionToken,
+		AWSConfigFile:       awsConfigFile,
+		AWSCredentialsFile:  awsCredentialsFile,
+		AWSProfile:          awsProfile,
+		AWSWebIdentityToken: awsWebIdentityToken,
+		AWSRoleARN:          awsRoleArn,
 	}
 
-	awsClient, awsClientErr := dagModule.setupAWSCredentials(ctx, awsAccessKeyID, awsSecretAccessKey, awsRegion)
-	if awsClientErr != nil {
+	// Only the inputs are kept in the module, since the client doesn't outlive this call. It's built
+	// here to fail early on invalid credentials, and built again by each function that uses it.
+	if _, awsClientErr := dagModule.setupAWSCredentials(ctx); awsClientErr != nil {
 		return nil, awsClientErr
 	}
 
-	dagModule.AWSClient = awsClient
-
 	// Only process configuration if a config file is provided
 	if config != nil {
 		cfgLoader := newCfg()
@@ -109,13 +192,14 @@ func New(
 	return dagModule, nil
 }
 
-// setupAWSCredentials validates and sets up AWS credentials for the module.
+// setupAWSCredentials validates the AWS inputs of the module and builds the AWS client with them.
+//
+// Exactly one source of credentials must be set: static keys (with an optional session token),
+// shared config/credentials files with a profile, or a web identity token with a role ARN.
+// The region defaults to us-east-1 if it's not set.
 //
 // Parameters:
 // - ctx: The context for the operation.
-// - awsAccessKeyID: The AWS access key ID secret.
-// - awsSecretAccessKey: The AWS secret access key secret.
-// - awsRegion: The AWS region to use (defaults to us-east-1 if empty).
 //
 // Returns:
 // - *AWSClient: A configured AWS client.
@@ -123,39 +207,42 @@ func New(
 func (m *AwsTagInspector) setupAWSCredentials(
 	// ctx is the context for the setupAWSCredentials function
 	ctx context.Context,
-	// awsAccessKeyID is the AWS access key ID secret
-	awsAccessKeyID *dagger.Secret,
-	// awsSecretAccessKey is the AWS secret access key secret
-	awsSecretAccessKey *dagger.Secret,
-	// awsRegion is the AWS region to use (defaults to us-east-1 if empty)
-	awsRegion string,
 ) (*AWSClient, error) {
 	// Ensure awsRegion has a default value, but only if it's empty
+	awsRegion := m.AWSRegion
 	if awsRegion == "" {
 		awsRegion = "us-east-1"
 	}
 
-	// Validate input parameters before processing
-	if awsAccessKeyID == nil || awsSecretAccessKey == nil {
-		return nil, Errorf("AWS access key ID and secret access key are required")
+	creds := awsCredentials{
+		accessKeyID:      m.AWSAccessKeyID,
+		secretAccessKey:  m.AWSSecretAccessKey,
+		sessionToken:     m.AWSSessionToken,
+		configFile:       m.AWSConfigFile,
+		credentialsFile:  m.AWSCredentialsFile,
+		profile:          m.AWSProfile,
+		webIdentityToken: m.AWSWebIdentityToken,
+		roleARN:          m.AWSRoleARN,
 	}
 
-	accessKeyValue, accessKeyErr := awsAccessKeyID.Plaintext(ctx)
-	if accessKeyErr != nil {
-		return nil, WrapError(accessKeyErr, "failed to get AWS access key ID")
+	sharedFilesDir, sharedFilesDirErr := os.MkdirTemp("", sharedFilesDirPattern)
+	if sharedFilesDirErr != nil {
+		return nil, WrapError(sharedFilesDirErr, "failed to create a directory for the AWS shared files")
 	}
 
-	secretAccessKeyValue, secretAccessKeyErr := awsSecretAccessKey.Plaintext(ctx)
-	if secretAccessKeyErr != nil {
-		return nil, WrapError(secretAccessKeyErr, "failed to get AWS secret access key")
+	// The shared files are read when the AWS configuration is loaded, so they aren't needed once the
+	// client is built.
+	defer os.RemoveAll(sharedFilesDir)
+
+	clientCfg, clientCfgErr := creds.toClientConfig(ctx, sharedFilesDir)
+	if clientCfgErr != nil {
+		return nil, clientCfgErr
 	}
 
-	awsClient, awsClientErr := NewAWSClient(ctx, AWSClientConfig{
-		Region:          awsRegion,
-		AccessKeyID:     accessKeyValue,
-		SecretAccessKey: secretAccessKeyValue,
-	})
+	clientCfg.Region = awsRegion
+	clientCfg.Endpoint = m.AWSEndpoint
 
+	awsClient, awsClientErr := NewAWSClient(ctx, clientCfg)
 	if awsClientErr != nil {
 		return nil, WrapError(awsClientErr, "failed to create AWS client")
 	}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/Excoriate/daggerverse/aws-tag-inspector/internal/dagger"
 )
@@ -17,16 +18,27 @@ type resourceScanner interface {
 // Scan performs a comprehensive AWS resource tag inspection and validation.
 //
 // This method dynamically scans AWS resources based on the configuration,
-// supporting extensibility for multiple resource types.
+// supporting extensibility for multiple resource types. The regions passed to the
+// module are scanned concurrently, and their results are merged into a single report.
+// If the configuration lists member accounts, the full scan runs in each of them,
+// with the role assumed through STS.
+//
+// The report can be rendered in several formats, all from the same scan results: JSON (default),
+// CSV for spreadsheets, Markdown for PR comments, a standalone HTML page with filters, and SARIF
+// for code-scanning dashboards.
 //
 // Parameters:
 //   - ctx: Optional context for controlling the scan operation's lifecycle and timeout.
+//   - format: The format of the report: json, csv, markdown, html or sarif. Default is json.
 //
 // Returns:
-//   - A Dagger file containing the scan results in JSON format.
+//   - A Dagger file containing the scan results in the given format.
 //   - An error if the scan process encounters any critical failures.
 func (m *AwsTagInspector) Scan(
 	ctx context.Context,
+	// format is the format of the report: json, csv, markdown, html or sarif. Default is json.
+	// +optional
+	format string,
 ) (*dagger.File, error) {
 	if m.Cfg == nil {
 		return nil, Errorf("configuration is required for scanning")
@@ -36,7 +48,40 @@ func (m *AwsTagInspector) Scan(
 		return nil, Errorf("scanning is globally disabled in configuration")
 	}
 
-	var allResults []ScanResult
+	// Scan the configured resources in every account and region
+	allResults, err := m.scanAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Render the results in the requested format
+	report, reportFileName, err := m.renderReport(format, allResults)
+	if err != nil {
+		return nil, WrapError(err, "failed to format scan results")
+	}
+
+	// Get a container from the AWS client
+	container := m.Ctr
+	if container == nil {
+		return nil, Errorf("failed to get container for results")
+	}
+
+	// Create a file in the ocntainer
+	resultsFile := container.Directory("mnt/").
+		WithNewFile(reportFileName, report)
+
+	// return the file only, extracted from the container.
+	return resultsFile.File(reportFileName), nil
+}
+
+// scanRegion scans the configured resources in a single region, with a client of that region.
+// Global resource types (e.g. s3) are only scanned if includeGlobal is set, so they're scanned once.
+func (m *AwsTagInspector) scanRegion(
+	ctx context.Context,
+	awsClient *AWSClient,
+	includeGlobal bool,
+) ([]ScanResult, error) {
+	var results []ScanResult
 
 	// Dynamically scan configured resources
 	for resourceType, resourceConfig := range m.Cfg.Resources {
@@ -45,62 +90,57 @@ func (m *AwsTagInspector) Scan(
 			continue
 		}
 
-		// Determine tag criteria (resource-specific or global)
-		tagCriteria := resourceConfig.TagCriteria
-		if reflect.DeepEqual(tagCriteria, TagCriteria{}) {
-			tagCriteria = m.Cfg.Global.TagCriteria
-		}
-
-		// Ensure compliance level is set, prioritizing resource-specific configuration
-		if tagCriteria.ComplianceLevel == "" {
-			// If resource-specific compliance level is empty, use global compliance level
-			if m.Cfg.Global.TagCriteria.ComplianceLevel != "" {
-				tagCriteria.ComplianceLevel = m.Cfg.Global.TagCriteria.ComplianceLevel
-			}
+		// Skip global resources, unless this region is the one scanning them
+		if globalResourceTypes[resourceType] && !includeGlobal {
+			continue
 		}
 
 		// Scan based on resource type
-		results, err := m.scanResourceByType(ctx, resourceType, tagCriteria)
+		resourceResults, err := m.scanResourceByType(ctx, awsClient, resourceType, m.resourceTagCriteria(resourceType))
 		if err != nil {
 			return nil, WrapError(err, fmt.Sprintf("failed to scan %s resources", resourceType))
 		}
 
-		allResults = append(allResults, results...)
+		results = append(results, resourceResults...)
 	}
 
-	// Convert results to JSON
-	jsonContent, err := m.formatResults(allResults)
-	if err != nil {
-		return nil, WrapError(err, "failed to format scan results")
-	}
+	return results, nil
+}
 
-	// Get a container from the AWS client
-	container := m.Ctr
-	if container == nil {
-		return nil, Errorf("failed to get container for results")
+// resourceTagCriteria returns the tag criteria of a resource type of the configuration: its own
+// criteria, or the global ones if it doesn't define any.
+func (m *AwsTagInspector) resourceTagCriteria(resourceType string) TagCriteria {
+	// Determine tag criteria (resource-specific or global)
+	tagCriteria := m.Cfg.Resources[resourceType].TagCriteria
+	if reflect.DeepEqual(tagCriteria, TagCriteria{}) {
+		tagCriteria = m.Cfg.Global.TagCriteria
 	}
 
-	// Create a file in the ocntainer
-	resultsFile := container.Directory("mnt/").
-		WithNewFile("scan-results.json", jsonContent)
+	// Ensure compliance level is set, prioritizing resource-specific configuration
+	if tagCriteria.ComplianceLevel == "" {
+		// If resource-specific compliance level is empty, use global compliance level
+		if m.Cfg.Global.TagCriteria.ComplianceLevel != "" {
+			tagCriteria.ComplianceLevel = m.Cfg.Global.TagCriteria.ComplianceLevel
+		}
+	}
 
-	// return the file only, extracted from the container.
-	return resultsFile.File("scan-results.json"), nil
+	return tagCriteria
 }
 
 // scanResourceByType dynamically scans a specific resource type
 func (m *AwsTagInspector) scanResourceByType(
 	ctx context.Context,
+	awsClient *AWSClient,
 	resourceType string,
 	tagCriteria TagCriteria,
 ) ([]ScanResult, error) {
-	if m.AWSClient == nil {
+	if awsClient == nil {
 		return nil, Errorf("AWS client is not initialized")
 	}
 
 	switch resourceType {
 	case "s3":
-		s3Scanner, err := NewS3Scanner(ctx, m.AWSClient, m.Cfg)
+		s3Scanner, err := NewS3Scanner(ctx, awsClient, m.Cfg)
 		if err != nil {
 			return nil, WrapError(err, "failed to create S3 scanner")
 		}
@@ -110,48 +150,129 @@ func (m *AwsTagInspector) scanResourceByType(
 			return nil, WrapError(err, "failed to scan S3 buckets")
 		}
 		return results, nil
-	// Add more resource types here as they are implemented
-	// case "ec2":
-	// 	return m.scanEC2InstancesAPI(ctx, tagCriteria)
+	case "ec2":
+		ec2Scanner, err := NewEC2Scanner(ctx, awsClient, m.Cfg)
+		if err != nil {
+			return nil, WrapError(err, "failed to create EC2 scanner")
+		}
+
+		results, err := ec2Scanner.Scan(tagCriteria)
+		if err != nil {
+			return nil, WrapError(err, "failed to scan EC2 instances")
+		}
+		return results, nil
+	// Any other resource type is scanned through the Resource Groups Tagging API, by its
+	// tagging API type name, e.g., "lambda:function" or "rds:db".
 	default:
-		return nil, fmt.Errorf("unsupported resource type: %s", resourceType)
+		if !isTaggingAPIResourceType(resourceType) {
+			return nil, fmt.Errorf("unsupported resource type: %s", resourceType)
+		}
+
+		taggingScanner, err := NewTaggingAPIScanner(ctx, awsClient, m.Cfg, resourceType)
+		if err != nil {
+			return nil, WrapError(err, "failed to create tagging API scanner")
+		}
+
+		results, err := taggingScanner.Scan(tagCriteria)
+		if err != nil {
+			return nil, WrapError(err, fmt.Sprintf("failed to scan %s resources", resourceType))
+		}
+		return results, nil
 	}
 }
 
-// formatResults converts scan results to a JSON string
-func (m *AwsTagInspector) formatResults(results []ScanResult) (string, error) {
-	if len(results) == 0 {
-		return "[]", nil
+// complianceSummary holds the compliance counts of a group of resources, e.g. of a region or an account.
+type complianceSummary struct {
+	TotalResources int `json:"total_resources"`
+	Compliant      int `json:"compliant"`
+	NonCompliant   int `json:"non_compliant"`
+}
+
+// add returns the summary with the given result counted
+func (s complianceSummary) add(result ScanResult) complianceSummary {
+	s.TotalResources++
+
+	if result.ComplianceTag == "compliant" {
+		s.Compliant++
+	} else {
+		s.NonCompliant++
 	}
 
-	// Group results by resource type
+	return s
+}
+
+// scanSummary is the report of a scan: the results grouped by resource type, the compliance counts
+// (overall, per region and per account), and the excluded resources with their reason.
+type scanSummary struct {
+	TotalResources int                     `json:"total_resources"`
+	ResourceTypes  map[string][]ScanResult `json:"resource_types"`
+	Compliance     struct {
+		Compliant    int `json:"compliant"`
+		NonCompliant int `json:"non_compliant"`
+	} `json:"compliance"`
+	Regions  map[string]complianceSummary `json:"regions"`
+	Accounts map[string]complianceSummary `json:"accounts,omitempty"`
+	Excluded []ScanResult                 `json:"excluded"`
+}
+
+// summarizeResults groups the scan results and calculates their compliance counts
+func summarizeResults(results []ScanResult) scanSummary {
+	// Group results by resource type. Excluded resources are reported apart, with their reason,
+	// and aren't counted in the compliance stats.
 	groupedResults := make(map[string][]ScanResult)
+	excludedResults := make([]ScanResult, 0)
+
+	var scannedResults []ScanResult
+
 	for _, result := range results {
+		if result.ComplianceTag == complianceTagExcluded {
+			excludedResults = append(excludedResults, result)
+
+			continue
+		}
+
+		scannedResults = append(scannedResults, result)
 		groupedResults[result.ResourceType] = append(groupedResults[result.ResourceType], result)
 	}
 
-	// Format summary
-	summary := struct {
-		TotalResources int                     `json:"total_resources"`
-		ResourceTypes  map[string][]ScanResult `json:"resource_types"`
-		Compliance     struct {
-			Compliant    int `json:"compliant"`
-			NonCompliant int `json:"non_compliant"`
-		} `json:"compliance"`
-	}{
-		TotalResources: len(results),
+	// Format summary. The overall counts cover every scanned account (i.e. the organisation).
+	summary := scanSummary{
+		TotalResources: len(scannedResults),
 		ResourceTypes:  groupedResults,
+		Regions:        make(map[string]complianceSummary),
+		Excluded:       excludedResults,
 	}
 
-	// Calculate compliance stats
-	for _, result := range results {
+	// Calculate compliance stats, overall, per region and per account
+	for _, result := range scannedResults {
 		if result.ComplianceTag == "compliant" {
 			summary.Compliance.Compliant++
 		} else {
 			summary.Compliance.NonCompliant++
 		}
+
+		summary.Regions[result.Region] = summary.Regions[result.Region].add(result)
+
+		if result.AccountID != "" {
+			if summary.Accounts == nil {
+				summary.Accounts = make(map[string]complianceSummary)
+			}
+
+			summary.Accounts[result.AccountID] = summary.Accounts[result.AccountID].add(result)
+		}
 	}
 
+	return summary
+}
+
+// formatResults converts scan results to a JSON string
+func (m *AwsTagInspector) formatResults(results []ScanResult) (string, error) {
+	if len(results) == 0 {
+		return "[]", nil
+	}
+
+	summary := summarizeResults(results)
+
 	// Marshal to JSON with indentation for readability
 	jsonBytes, err := json.MarshalIndent(summary, "", "  ")
 	if err != nil {
@@ -161,6 +282,48 @@ func (m *AwsTagInspector) formatResults(results []ScanResult) (string, error) {
 	return string(jsonBytes), nil
 }
 
+// loadScanResults returns the results of the given JSON scan report or, if none is passed, of a new scan
+func (m *AwsTagInspector) loadScanResults(ctx context.Context, scanResults *dagger.File) ([]ScanResult, error) {
+	if scanResults == nil {
+		if !m.Cfg.Global.Enabled {
+			return nil, Errorf("scanning is globally disabled in configuration")
+		}
+
+		return m.scanAccounts(ctx)
+	}
+
+	report, err := scanResults.Contents(ctx)
+	if err != nil {
+		return nil, WrapError(err, "failed to read the scan results")
+	}
+
+	results, err := parseScanReport(report)
+	if err != nil {
+		return nil, WrapError(err, "failed to parse the scan results, expected the JSON report of Scan")
+	}
+
+	return results, nil
+}
+
+// parseScanReport returns the resources of a JSON scan report, the scanned and the excluded ones
+func parseScanReport(report string) ([]ScanResult, error) {
+	if strings.TrimSpace(report) == "[]" {
+		return nil, nil
+	}
+
+	var summary scanSummary
+	if err := json.Unmarshal([]byte(report), &summary); err != nil {
+		return nil, err
+	}
+
+	results := append([]ScanResult{}, summary.Excluded...)
+	for _, resourceResults := range summary.ResourceTypes {
+		results = append(results, resourceResults...)
+	}
+
+	return sortedResults(results), nil
+}
+
 // ValidateConfig validates the configuration file for the AWS Tag Inspector.
 //
 // This method performs comprehensive validation of the provided configuration file,
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// defaultAssumeRoleSessionName is the session name of the roles assumed in the member accounts.
+	defaultAssumeRoleSessionName = "aws-tag-inspector"
+)
+
+// scanAccounts runs the full scan (every configured region) in each member account of the
+// configuration, with the role assumed through STS, and labels each result with its account ID.
+// If no account is configured, only the account of the module's credentials is scanned.
+func (m *AwsTagInspector) scanAccounts(ctx context.Context) ([]ScanResult, error) {
+	awsClient, err := m.setupAWSCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := m.Cfg.Accounts
+	if len(accounts.IDs) == 0 {
+		return m.scanAccount(ctx, awsClient)
+	}
+
+	var allResults []ScanResult
+
+	// Accounts are scanned one after the other, since their regions are already scanned concurrently.
+	for _, accountID := range accounts.IDs {
+		accountClient, err := m.accountClient(ctx, awsClient, accountID)
+		if err != nil {
+			return nil, WrapError(err, fmt.Sprintf("failed to access account %s", accountID))
+		}
+
+		results, err := m.scanAccount(ctx, accountClient)
+		if err != nil {
+			return nil, WrapError(err, fmt.Sprintf("failed to scan account %s", accountID))
+		}
+
+		for idx := range results {
+			results[idx].AccountID = accountID
+		}
+
+		allResults = append(allResults, results...)
+	}
+
+	return allResults, nil
+}
+
+// scanAccount scans the configured regions of a single account, with the given client.
+func (m *AwsTagInspector) scanAccount(ctx context.Context, awsClient *AWSClient) ([]ScanResult, error) {
+	regions, err := m.resolveRegions(ctx, awsClient)
+	if err != nil {
+		return nil, WrapError(err, "failed to resolve the regions to scan")
+	}
+
+	// Scan the configured resources in every region, concurrently
+	return m.scanRegions(ctx, awsClient, regions)
+}
+
+// accountClient returns a client with the role of the configuration assumed in a member account,
+// with the credentials of the given client.
+func (m *AwsTagInspector) accountClient(
+	ctx context.Context,
+	awsClient *AWSClient,
+	accountID string,
+) (*AWSClient, error) {
+	accounts := m.Cfg.Accounts
+	if accounts.RoleName == "" {
+		return nil, fmt.Errorf("no role configured to access account %s", accountID)
+	}
+
+	sessionName := accounts.SessionName
+	if sessionName == "" {
+		sessionName = defaultAssumeRoleSessionName
+	}
+
+	roleARN := fmt.Sprintf("arn:aws:iam::%s:role/%s", accountID, accounts.RoleName)
+
+	return awsClient.AssumeRole(ctx, roleARN, accounts.ExternalID, sessionName)
+}
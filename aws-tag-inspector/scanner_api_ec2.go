@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const (
+	// ec2MinPageSize and ec2MaxPageSize are the limits of MaxResults in DescribeInstances.
+	ec2MinPageSize = 5
+	ec2MaxPageSize = 1000
+	// ec2NameTag is the tag that holds the name of an instance, also matched by the exclusion patterns.
+	ec2NameTag = "Name"
+)
+
+// EC2Scanner implements the Scanner interface for EC2 instances
+type EC2Scanner struct {
+	BaseResource
+	client    *ec2.Client
+	ctx       context.Context
+	batchSize int
+	config    *inspectorConfig
+}
+
+// NewEC2Scanner creates a new EC2 scanner instance
+func NewEC2Scanner(
+	ctx context.Context,
+	awsClient *AWSClient,
+	config *inspectorConfig,
+	opts ...EC2ScannerOption,
+) (*EC2Scanner, error) {
+	if awsClient == nil {
+		return nil, fmt.Errorf("AWS client cannot be nil")
+	}
+
+	client, err := awsClient.GetEC2Client()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize EC2 client: %w", err)
+	}
+
+	scanner := &EC2Scanner{
+		BaseResource: BaseResource{
+			ResourceType: "ec2:instance",
+			Region:       awsClient.cfg.Region,
+			Tags:         make(map[string]string),
+			Metadata:     make(map[string]interface{}),
+		},
+		client:    client,
+		ctx:       ctx,
+		batchSize: 100, // Default page size
+		config:    config,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(scanner)
+	}
+
+	// Apply configuration if available
+	if config != nil {
+		if resourceConfig, exists := config.Resources["ec2"]; exists && resourceConfig.Enabled {
+			if resourceConfig.BatchSize != nil {
+				scanner.batchSize = *resourceConfig.BatchSize
+			}
+		}
+	}
+
+	return scanner, nil
+}
+
+// EC2ScannerOption defines functional options for EC2Scanner
+type EC2ScannerOption func(*EC2Scanner)
+
+// WithEC2PageSize sets the number of instances requested per DescribeInstances page
+func WithEC2PageSize(size int) EC2ScannerOption {
+	return func(s *EC2Scanner) {
+		if size > 0 {
+			s.batchSize = size
+		}
+	}
+}
+
+// Scan implements the Scanner interface for EC2 instances.
+// Terminated instances are skipped, since they can't be tagged anymore.
+func (s *EC2Scanner) Scan(criteria TagCriteria) ([]ScanResult, error) {
+	paginator := ec2.NewDescribeInstancesPaginator(s.client, &ec2.DescribeInstancesInput{
+		MaxResults: aws.Int32(s.pageSize()),
+	})
+
+	var results []ScanResult
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(s.ctx)
+		if err != nil {
+			return results, fmt.Errorf("failed to describe instances: %w", err)
+		}
+
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.State != nil && instance.State.Name == types.InstanceStateNameTerminated {
+					continue
+				}
+
+				results = append(results, s.scanInstance(aws.ToString(reservation.OwnerId), instance, criteria))
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// pageSize returns the batch size within the limits accepted by DescribeInstances
+func (s *EC2Scanner) pageSize() int32 {
+	switch {
+	case s.batchSize < ec2MinPageSize:
+		return ec2MinPageSize
+	case s.batchSize > ec2MaxPageSize:
+		return ec2MaxPageSize
+	default:
+		return int32(s.batchSize)
+	}
+}
+
+// scanInstance scans a single instance for tag compliance.
+// If the instance is excluded from scanning, its tags aren't scanned and the reason is reported.
+func (s *EC2Scanner) scanInstance(ownerID string, instance types.Instance, criteria TagCriteria) ScanResult {
+	instanceID := aws.ToString(instance.InstanceId)
+
+	tags := make(map[string]string, len(instance.Tags))
+	for _, tag := range instance.Tags {
+		if tag.Key != nil {
+			tags[*tag.Key] = aws.ToString(tag.Value)
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"InstanceType": string(instance.InstanceType),
+		"State":        "",
+	}
+
+	if instance.State != nil {
+		metadata["State"] = string(instance.State.Name)
+	}
+
+	if instance.LaunchTime != nil {
+		metadata["LaunchTime"] = instance.LaunchTime.String()
+	}
+
+	if instance.Placement != nil && instance.Placement.AvailabilityZone != nil {
+		metadata["AvailabilityZone"] = *instance.Placement.AvailabilityZone
+	}
+
+	instanceResource := BaseResource{
+		ResourceType: s.ResourceType,
+		ResourceID:   instanceID,
+		ARN:          fmt.Sprintf("arn:aws:ec2:%s:%s:instance/%s", s.Region, ownerID, instanceID),
+		Region:       s.Region,
+		Tags:         tags,
+		Metadata:     metadata,
+	}
+
+	if excluded, reason := s.isInstanceExcluded(instanceID, tags[ec2NameTag]); excluded {
+		return newExcludedResult(instanceResource, reason)
+	}
+
+	return newScanResult(instanceResource, criteria, s.config)
+}
+
+// isInstanceExcluded checks if an instance matches the excluded_resources of the ec2 configuration,
+// either by its ID or by its Name tag
+func (s *EC2Scanner) isInstanceExcluded(instanceID, name string) (bool, string) {
+	loader := &configLoader{config: s.config}
+
+	for _, candidate := range []string{instanceID, name} {
+		if candidate == "" {
+			continue
+		}
+
+		if excluded, reason := loader.isResourceExcluded("ec2", candidate); excluded {
+			return true, reason
+		}
+	}
+
+	return false, ""
+}
@@ -4,13 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"regexp"
-	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go"
+	"golang.org/x/sync/errgroup"
 )
 
 // S3Scanner implements the Scanner interface for S3 buckets
@@ -80,54 +79,64 @@ func WithBatchSize(size int) S3ScannerOption {
 	}
 }
 
-// Scan implements the Scanner interface for S3 buckets
+// Scan implements the Scanner interface for S3 buckets.
+// Up to batchSize buckets are scanned concurrently. Excluded buckets are reported without
+// calling their APIs, since e.g. state buckets are often restricted.
 func (s *S3Scanner) Scan(criteria TagCriteria) ([]ScanResult, error) {
 	buckets, err := s.listBuckets()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list buckets: %w", err)
 	}
 
-	results := make([]ScanResult, 0, len(buckets))
-	errChan := make(chan error)
-	resultChan := make(chan ScanResult)
+	bucketResults := make([]ScanResult, len(buckets))
+	bucketErrors := make([]error, len(buckets))
 
-	sem := make(chan struct{}, s.batchSize)
-	var wg sync.WaitGroup
+	var scanners errgroup.Group
+	scanners.SetLimit(s.batchSize)
 
-	// Process buckets concurrently
-	go func() {
-		wg.Wait()
-		close(resultChan)
-		close(errChan)
-	}()
+	for idx, bucket := range buckets {
+		bucketName := aws.ToString(bucket.Name)
 
-	for _, bucket := range buckets {
-		wg.Add(1)
-		sem <- struct{}{} // Acquire semaphore
+		if excluded, reason := s.isBucketExcluded(bucketName); excluded {
+			bucketResults[idx] = newExcludedResult(BaseResource{
+				ResourceType: s.ResourceType,
+				ResourceID:   bucketName,
+				ARN:          fmt.Sprintf("arn:aws:s3:::%s", bucketName),
+			}, reason)
 
-		go func(bucket types.Bucket) {
-			defer wg.Done()
-			defer func() { <-sem }() // Release semaphore
+			continue
+		}
 
-			result, err := s.scanBucket(*bucket.Name, criteria)
+		// Each bucket writes to its own index, so no lock is needed. The errors are collected
+		// instead of returned, so a failing bucket doesn't cancel the others.
+		scanners.Go(func() error {
+			result, err := s.scanBucket(bucketName, criteria)
 			if err != nil {
-				errChan <- fmt.Errorf("failed to scan bucket %s: %w", *bucket.Name, err)
-				return
+				bucketErrors[idx] = fmt.Errorf("failed to scan bucket %s: %w", bucketName, err)
+
+				return nil
 			}
-			resultChan <- result
-		}(bucket)
+
+			bucketResults[idx] = result
+
+			return nil
+		})
 	}
 
-	// Collect results and errors
+	_ = scanners.Wait()
+
+	results := make([]ScanResult, 0, len(buckets))
+
 	var scanErrors []error
-	go func() {
-		for err := range errChan {
-			scanErrors = append(scanErrors, err)
+
+	for idx := range buckets {
+		if bucketErrors[idx] != nil {
+			scanErrors = append(scanErrors, bucketErrors[idx])
+
+			continue
 		}
-	}()
 
-	for result := range resultChan {
-		results = append(results, result)
+		results = append(results, bucketResults[idx])
 	}
 
 	if len(scanErrors) > 0 {
@@ -156,12 +165,17 @@ func (s *S3Scanner) scanBucket(bucketName string, criteria TagCriteria) (ScanRes
 		return ScanResult{}, err
 	}
 
-	// Initialize result
-	result := ScanResult{
+	// Buckets in us-east-1 have an empty location constraint
+	bucketRegion := string(location.LocationConstraint)
+	if bucketRegion == "" {
+		bucketRegion = "us-east-1"
+	}
+
+	bucket := BaseResource{
 		ResourceType: s.ResourceType,
 		ResourceID:   bucketName,
 		ARN:          fmt.Sprintf("arn:aws:s3:::%s", bucketName),
-		Region:       string(location.LocationConstraint),
+		Region:       bucketRegion,
 		Tags:         make(map[string]string),
 		Metadata: map[string]interface{}{
 			"CreationDate": "", // Will be populated if available
@@ -177,44 +191,26 @@ func (s *S3Scanner) scanBucket(bucketName string, criteria TagCriteria) (ScanRes
 	if err != nil {
 		// Check if the error is because there are no tags
 		var apiErr smithy.APIError
-		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchTagSet" {
-			// This is fine - bucket just has no tags
-			result.Tags = make(map[string]string)
-		} else {
-			return result, fmt.Errorf("failed to get bucket tags: %w", err)
+		if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "NoSuchTagSet" {
+			return ScanResult{}, fmt.Errorf("failed to get bucket tags: %w", err)
 		}
 	} else if tags != nil {
 		// Convert tags to map
 		for _, tag := range tags.TagSet {
 			if tag.Key != nil && tag.Value != nil {
-				result.Tags[*tag.Key] = *tag.Value
+				bucket.Tags[*tag.Key] = *tag.Value
 			}
 		}
 	}
 
-	// Determine compliance level from tags or default
-	if level, exists := s.Tags["ComplianceLevel"]; exists {
-		criteria.ComplianceLevel = level
-	} else if s.config != nil {
-		// Set default compliance level from global config if available
+	// Set default compliance level from global config if available. It can be overridden per
+	// bucket with the ComplianceLevel tag.
+	if criteria.ComplianceLevel == "" && s.config != nil {
 		criteria.ComplianceLevel = s.config.Global.TagCriteria.ComplianceLevel
 	}
 
-	// Scan tags using base implementation, passing compliance levels
-	if s.config != nil {
-		result.Issues = s.ScanTags(criteria, s.config.ComplianceLevels)
-	} else {
-		result.Issues = s.ScanTags(criteria, nil)
-	}
-
-	// Set compliance tag based on issues
-	if len(result.Issues) == 0 {
-		result.ComplianceTag = "compliant"
-	} else {
-		result.ComplianceTag = "non-compliant"
-	}
-
-	return result, nil
+	// Scan the tags of the bucket (not the ones of the scanner)
+	return newScanResult(bucket, criteria, s.config), nil
 }
 
 // GetBucketMetadata retrieves additional metadata for a bucket
@@ -250,20 +246,12 @@ func (s *S3Scanner) GetBucketMetadata(bucketName string) (map[string]interface{}
 
 // IsExcluded checks if a resource should be excluded from scanning
 func (s *S3Scanner) IsExcluded() (bool, string) {
-	if s.config == nil {
-		return false, ""
-	}
-
-	resourceConfig, exists := s.config.Resources["s3"]
-	if !exists || !resourceConfig.Enabled {
-		return false, ""
-	}
+	return s.isBucketExcluded(s.ResourceID)
+}
 
-	for _, excluded := range resourceConfig.ExcludedResources {
-		if matched, _ := regexp.MatchString(excluded.Pattern, s.ResourceID); matched {
-			return true, excluded.Reason
-		}
-	}
+// isBucketExcluded checks if a bucket matches the excluded_resources of the s3 configuration, by its name
+func (s *S3Scanner) isBucketExcluded(bucketName string) (bool, string) {
+	loader := &configLoader{config: s.config}
 
-	return false, ""
+	return loader.isResourceExcluded("s3", bucketName)
 }
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+)
+
+const (
+	// taggingAPIMaxPageSize is the maximum value of ResourcesPerPage in GetResources.
+	taggingAPIMaxPageSize = 100
+)
+
+// taggingAPIResourceTypeRegex matches the resource type filters of the Resource Groups Tagging API,
+// in the form "service[:resourceType]", e.g., "lambda:function", "rds:db" or "dynamodb".
+var taggingAPIResourceTypeRegex = regexp.MustCompile(`^[a-z0-9-]+(:[a-z0-9-]+)?$`)
+
+// TaggingAPIScanner implements the Scanner interface for any resource type supported by the
+// Resource Groups Tagging API (tagging:GetResources).
+//
+// The Tagging API only returns the resources that are tagged, or that were tagged at some point,
+// so resources that never had a tag aren't reported. The dedicated scanners (s3, ec2) list every
+// resource, and add service-specific metadata.
+type TaggingAPIScanner struct {
+	BaseResource
+	client    *resourcegroupstaggingapi.Client
+	ctx       context.Context
+	batchSize int
+	config    *inspectorConfig
+	// configKey is the key of the resource type in the resources section of the configuration.
+	configKey string
+}
+
+// NewTaggingAPIScanner creates a new scanner for the given Tagging API resource type, e.g., "lambda:function"
+func NewTaggingAPIScanner(
+	ctx context.Context,
+	awsClient *AWSClient,
+	config *inspectorConfig,
+	resourceType string,
+	opts ...TaggingAPIScannerOption,
+) (*TaggingAPIScanner, error) {
+	if awsClient == nil {
+		return nil, fmt.Errorf("AWS client cannot be nil")
+	}
+
+	if !isTaggingAPIResourceType(resourceType) {
+		return nil, fmt.Errorf("invalid resource type for the tagging API: %s, expected service[:resourceType]",
+			resourceType)
+	}
+
+	client, err := awsClient.GetTaggingClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tagging API client: %w", err)
+	}
+
+	scanner := &TaggingAPIScanner{
+		BaseResource: BaseResource{
+			ResourceType: resourceType,
+			Region:       awsClient.cfg.Region,
+			Tags:         make(map[string]string),
+			Metadata:     make(map[string]interface{}),
+		},
+		client:    client,
+		ctx:       ctx,
+		batchSize: taggingAPIMaxPageSize, // Default page size
+		config:    config,
+		configKey: resourceType,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(scanner)
+	}
+
+	// Apply configuration if available
+	if config != nil {
+		if resourceConfig, exists := config.Resources[resourceType]; exists && resourceConfig.Enabled {
+			if resourceConfig.BatchSize != nil {
+				scanner.batchSize = *resourceConfig.BatchSize
+			}
+		}
+	}
+
+	return scanner, nil
+}
+
+// TaggingAPIScannerOption defines functional options for TaggingAPIScanner
+type TaggingAPIScannerOption func(*TaggingAPIScanner)
+
+// WithTaggingAPIPageSize sets the number of resources requested per GetResources page
+func WithTaggingAPIPageSize(size int) TaggingAPIScannerOption {
+	return func(s *TaggingAPIScanner) {
+		if size > 0 {
+			s.batchSize = size
+		}
+	}
+}
+
+// Scan implements the Scanner interface for the resources returned by the Tagging API
+func (s *TaggingAPIScanner) Scan(criteria TagCriteria) ([]ScanResult, error) {
+	pageSize := s.batchSize
+	if pageSize > taggingAPIMaxPageSize {
+		pageSize = taggingAPIMaxPageSize
+	}
+
+	paginator := resourcegroupstaggingapi.NewGetResourcesPaginator(s.client, &resourcegroupstaggingapi.GetResourcesInput{
+		ResourceTypeFilters: []string{s.ResourceType},
+		ResourcesPerPage:    aws.Int32(int32(pageSize)),
+	})
+
+	var results []ScanResult
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(s.ctx)
+		if err != nil {
+			return results, fmt.Errorf("failed to get %s resources from the tagging API: %w", s.ResourceType, err)
+		}
+
+		for _, mapping := range page.ResourceTagMappingList {
+			resource, err := s.resourceFromTagMapping(mapping)
+			if err != nil {
+				return results, err
+			}
+
+			if excluded, reason := s.isExcluded(resource); excluded {
+				results = append(results, newExcludedResult(resource, reason))
+
+				continue
+			}
+
+			results = append(results, newScanResult(resource, criteria, s.config))
+		}
+	}
+
+	return results, nil
+}
+
+// resourceFromTagMapping maps a resource returned by the Tagging API, identified by its ARN, to a resource
+func (s *TaggingAPIScanner) resourceFromTagMapping(mapping types.ResourceTagMapping) (BaseResource, error) {
+	resourceARN := aws.ToString(mapping.ResourceARN)
+
+	resourceType, resourceID, err := parseTaggedResourceARN(resourceARN)
+	if err != nil {
+		return BaseResource{}, err
+	}
+
+	parsedARN, _ := arn.Parse(resourceARN)
+
+	region := parsedARN.Region
+	if region == "" {
+		// Global resources (e.g., IAM or CloudFront) don't have a region in their ARN.
+		region = s.Region
+	}
+
+	tags := make(map[string]string, len(mapping.Tags))
+	for _, tag := range mapping.Tags {
+		if tag.Key != nil {
+			tags[*tag.Key] = aws.ToString(tag.Value)
+		}
+	}
+
+	return BaseResource{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		ARN:          resourceARN,
+		Region:       region,
+		Tags:         tags,
+		Metadata: map[string]interface{}{
+			"AccountID": parsedARN.AccountID,
+			"Source":    "tagging-api",
+		},
+	}, nil
+}
+
+// isExcluded checks if a resource matches the excluded_resources of its configuration,
+// either by its ID or by its ARN
+func (s *TaggingAPIScanner) isExcluded(resource BaseResource) (bool, string) {
+	loader := &configLoader{config: s.config}
+
+	for _, candidate := range []string{resource.ResourceID, resource.ARN} {
+		if excluded, reason := loader.isResourceExcluded(s.configKey, candidate); excluded {
+			return true, reason
+		}
+	}
+
+	return false, ""
+}
+
+// isTaggingAPIResourceType checks if the resource type is a valid Tagging API resource type filter
+func isTaggingAPIResourceType(resourceType string) bool {
+	return taggingAPIResourceTypeRegex.MatchString(resourceType)
+}
+
+// parseTaggedResourceARN returns the resource type (in the Tagging API form, "service:resourceType")
+// and the resource ID of an ARN. The resource part of an ARN can be "id", "type/id" or "type:id".
+func parseTaggedResourceARN(resourceARN string) (string, string, error) {
+	parsedARN, err := arn.Parse(resourceARN)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid ARN returned by the tagging API %s: %w", resourceARN, err)
+	}
+
+	resourceType, resourceID, found := strings.Cut(parsedARN.Resource, "/")
+	if !found {
+		resourceType, resourceID, found = strings.Cut(parsedARN.Resource, ":")
+	}
+
+	if !found {
+		return parsedARN.Service, parsedARN.Resource, nil
+	}
+
+	return parsedARN.Service + ":" + resourceType, resourceID, nil
+}
@@ -8,20 +8,29 @@ import (
 	"github.com/Excoriate/daggerverse/aws-tag-inspector/internal/dagger"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
-// AWSClientConfig holds the configuration for AWS clients
+// AWSClientConfig holds the configuration for AWS clients.
+// Exactly one source of credentials must be set: static keys (with an optional session token),
+// shared config/credentials files with a profile, or a web identity token with a role ARN.
 type AWSClientConfig struct {
-	AccessKeyID     string
-	SecretAccessKey string
-	Region          string
-	SessionToken    string
-	Profile         string
-	Endpoint        string
-	MaxRetries      int
+	AccessKeyID            string
+	SecretAccessKey        string
+	Region                 string
+	SessionToken           string
+	Profile                string
+	SharedConfigFiles      []string
+	SharedCredentialsFiles []string
+	WebIdentityRoleARN     string
+	WebIdentityToken       string
+	RoleSessionName        string
+	Endpoint               string
+	MaxRetries             int
 }
 
 // AWSClient represents an AWS client configuration
@@ -50,10 +59,18 @@ func (f *S3ClientFactory) CreateClient(cfg aws.Config) interface{} {
 	return s3.NewFromConfig(cfg)
 }
 
+// TaggingClientFactory implements ServiceClientFactory for the Resource Groups Tagging API
+type TaggingClientFactory struct{}
+
+func (f *TaggingClientFactory) CreateClient(cfg aws.Config) interface{} {
+	return resourcegroupstaggingapi.NewFromConfig(cfg)
+}
+
 // serviceFactories maps service names to their factories
 var serviceFactories = map[string]ServiceClientFactory{
-	"ec2": &EC2ClientFactory{},
-	"s3":  &S3ClientFactory{},
+	"ec2":     &EC2ClientFactory{},
+	"s3":      &S3ClientFactory{},
+	"tagging": &TaggingClientFactory{},
 }
 
 // NewAWSClient creates a new AWS client with the given configuration
@@ -67,43 +84,93 @@ func NewAWSClient(ctx context.Context, cfg AWSClientConfig) (*AWSClient, error)
 		return nil, fmt.Errorf("invalid AWS region format: %s", cfg.Region)
 	}
 
-	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
-		return nil, fmt.Errorf("AWS credentials are required")
+	source, err := cfg.credentialsSource()
+	if err != nil {
+		return nil, err
 	}
 
 	// Load AWS configuration with custom options
 	opts := []func(*config.LoadOptions) error{
 		config.WithRegion(cfg.Region),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			cfg.AccessKeyID,
-			cfg.SecretAccessKey,
-			cfg.SessionToken, // Include session token if provided
-		)),
 		config.WithRetryMaxAttempts(cfg.MaxRetries),
 	}
 
-	// Add custom endpoint if specified
+	opts = append(opts, cfg.credentialsLoadOptions(source)...)
+
+	// Add custom endpoint if specified, e.g., a local AWS stand-in such as LocalStack or moto.
 	if cfg.Endpoint != "" {
 		customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
 			return aws.Endpoint{
-				URL:           cfg.Endpoint,
-				SigningRegion: cfg.Region,
-				// Add support for different services
-		})
-			}, nil,
+				URL:               cfg.Endpoint,
+				SigningRegion:     region,
+				HostnameImmutable: true,
+			}, nil
 		})
 		opts = append(opts, config.WithEndpointResolverWithOptions(customResolver))
 	}
- err != nil {
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
+	if source == credentialsSourceWebIdentity {
+		awsCfg = cfg.withWebIdentityCredentials(awsCfg)
+	}
+
 	return &AWSClient{
 		cfg:        awsCfg,
 		serviceMap: make(map[string]interface{}),
 	}, nil
 }
 
+// ForRegion returns a new client with the same configuration and credentials, for another region
+func (c *AWSClient) ForRegion(region string) (*AWSClient, error) {
+	if !isValidAWSRegion(region) {
+		return nil, fmt.Errorf("invalid AWS region format: %s", region)
+	}
+
+	regionalCfg := c.cfg.Copy()
+	regionalCfg.Region = region
+
+	return &AWSClient{
+		cfg:        regionalCfg,
+		container:  c.container,
+		serviceMap: make(map[string]interface{}),
+	}, nil
+}
+
+// AssumeRole returns a new client, in the same region, with the credentials of the given role
+// assumed with STS. The credentials are retrieved right away, so a role that can't be assumed
+// fails here instead of in the middle of a scan.
+func (c *AWSClient) AssumeRole(ctx context.Context, roleARN, externalID, sessionName string) (*AWSClient, error) {
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(c.cfg), roleARN,
+		func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = sessionName
+			if externalID != "" {
+				o.ExternalID = aws.String(externalID)
+			}
+		})
+
+	assumedCfg := c.cfg.Copy()
+	assumedCfg.Credentials = aws.NewCredentialsCache(provider)
+
+	if _, err := assumedCfg.Credentials.Retrieve(ctx); err != nil {
+		return nil, fmt.Errorf("failed to assume role %s: %w", roleARN, err)
+	}
+
+	return &AWSClient{
+		cfg:        assumedCfg,
+		container:  c.container,
+		serviceMap: make(map[string]interface{}),
+	}, nil
+}
+
+// Region returns the region of the client
+func (c *AWSClient) Region() string {
+	return c.cfg.Region
+}
+
 // isValidAWSRegion validates the AWS region format
 func isValidAWSRegion(region string) bool {
 	// AWS region format: [a-z]{2}-[a-z]+-\d{1}
@@ -113,6 +180,12 @@ func isValidAWSRegion(region string) bool {
 	return match
 }
 
+// isValidAWSAccountID validates the AWS account ID format (12 digits)
+func isValidAWSAccountID(accountID string) bool {
+	match, _ := regexp.MatchString(`^\d{12}$`, accountID)
+	return match
+}
+
 // GetServiceClient returns a cached service client or creates a new one
 func (c *AWSClient) GetServiceClient(service string) (interface{}, error) {
 	if c.serviceMap == nil {
@@ -123,15 +196,13 @@ func (c *AWSClient) GetServiceClient(service string) (interface{}, error) {
 		return client, nil
 	}
 
-	var client interface{}
-	switch service {
-	case "s3":
-		client = s3.NewFromConfig(c.cfg)
-	// Add other services as needed
-	default:
+	factory, exists := serviceFactories[service]
+	if !exists {
 		return nil, fmt.Errorf("unsupported service: %s", service)
 	}
 
+	client := factory.CreateClient(c.cfg)
+
 	c.serviceMap[service] = client
 	return client, nil
 }
@@ -145,6 +216,15 @@ func (c *AWSClient) GetEC2Client() (*ec2.Client, error) {
 	return client.(*ec2.Client), nil
 }
 
+// GetTaggingClient returns a Resource Groups Tagging API client
+func (c *AWSClient) GetTaggingClient() (*resourcegroupstaggingapi.Client, error) {
+	client, err := c.GetServiceClient("tagging")
+	if err != nil {
+		return nil, err
+	}
+	return client.(*resourcegroupstaggingapi.Client), nil
+}
+
 // GetS3Client returns an S3 client with proper configuration
 func (c *AWSClient) GetS3Client() (*s3.Client, error) {
 	if c.serviceMap == nil {
@@ -155,14 +235,10 @@ func (c *AWSClient) GetS3Client() (*s3.Client, error) {
 		return client.(*s3.Client), nil
 	}
 
-	// Configure S3 specific options
-	s3Opts := s3.Options{
-		Region:       c.cfg.Region,
-		UsePathStyle: true, // Use path-style addressing for better compatibility
-	}
-
+	// Configure S3 specific options. The options built from the AWS config (credentials, endpoint
+	// resolver) are kept, so the client also works against a custom endpoint.
 	client := s3.NewFromConfig(c.cfg, func(o *s3.Options) {
-		*o = s3Opts
+		o.UsePathStyle = true // Use path-style addressing for better compatibility
 	})
 
 	c.serviceMap["s3"] = client
@@ -177,4 +253,4 @@ func (c *AWSClient) Container() *dagger.Container {
 // SetContainer sets the Dagger container for this client
 func (c *AWSClient) SetContainer(container *dagger.Container) {
 	c.container = container
-}
\ No newline at end of file
+}
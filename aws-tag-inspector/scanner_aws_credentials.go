@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Excoriate/daggerverse/aws-tag-inspector/internal/dagger"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// credentialsSource is the source of the credentials of the AWS client.
+type credentialsSource string
+
+const (
+	// credentialsSourceStatic uses an access key ID and a secret access key, with an optional session token.
+	credentialsSourceStatic credentialsSource = "static keys"
+	// credentialsSourceProfile uses a profile of the mounted shared config and credentials files.
+	credentialsSourceProfile credentialsSource = "shared config profile"
+	// credentialsSourceWebIdentity assumes a role with a web identity token, e.g. the OIDC token of a CI runner.
+	credentialsSourceWebIdentity credentialsSource = "web identity"
+	// defaultSharedConfigProfile is the profile used when the shared files are mounted without a profile.
+	defaultSharedConfigProfile = "default"
+	// sharedFilesDirPattern is the pattern of the temporary directory the shared files are written to,
+	// while the AWS client is built.
+	sharedFilesDirPattern = "aws-tag-inspector-*"
+)
+
+// awsCredentials holds the AWS credentials inputs of the module, as passed to New.
+type awsCredentials struct {
+	accessKeyID      *dagger.Secret
+	secretAccessKey  *dagger.Secret
+	sessionToken     *dagger.Secret
+	configFile       *dagger.File
+	credentialsFile  *dagger.File
+	profile          string
+	webIdentityToken *dagger.Secret
+	roleARN          string
+}
+
+// toClientConfig reads the secrets of the credentials inputs, writes their shared files to the given
+// directory, and returns the AWS client configuration with them. The region and the endpoint aren't set.
+func (c awsCredentials) toClientConfig(ctx context.Context, sharedFilesDir string) (AWSClientConfig, error) {
+	clientCfg := AWSClientConfig{
+		Profile:            c.profile,
+		WebIdentityRoleARN: c.roleARN,
+	}
+
+	secrets := []struct {
+		secret *dagger.Secret
+		target *string
+		name   string
+	}{
+		{c.accessKeyID, &clientCfg.AccessKeyID, "AWS access key ID"},
+		{c.secretAccessKey, &clientCfg.SecretAccessKey, "AWS secret access key"},
+		{c.sessionToken, &clientCfg.SessionToken, "AWS session token"},
+		{c.webIdentityToken, &clientCfg.WebIdentityToken, "web identity token"},
+	}
+
+	for _, s := range secrets {
+		if s.secret == nil {
+			continue
+		}
+
+		value, err := s.secret.Plaintext(ctx)
+		if err != nil {
+			return AWSClientConfig{}, WrapErrorf(err, "failed to get %s", s.name)
+		}
+
+		*s.target = strings.TrimSpace(value)
+	}
+
+	if c.configFile != nil {
+		path, err := writeSharedFile(ctx, c.configFile, sharedFilesDir, "config")
+		if err != nil {
+			return AWSClientConfig{}, err
+		}
+
+		clientCfg.SharedConfigFiles = []string{path}
+	}
+
+	if c.credentialsFile != nil {
+		path, err := writeSharedFile(ctx, c.credentialsFile, sharedFilesDir, "credentials")
+		if err != nil {
+			return AWSClientConfig{}, err
+		}
+
+		clientCfg.SharedCredentialsFiles = []string{path}
+	}
+
+	return clientCfg, nil
+}
+
+// identityToken is a web identity token held in memory, so it's never written to disk.
+type identityToken []byte
+
+// GetIdentityToken implements stscreds.IdentityTokenRetriever
+func (t identityToken) GetIdentityToken() ([]byte, error) {
+	return t, nil
+}
+
+// credentialsSource returns the source of the credentials set in the configuration.
+// Exactly one source must be set, so the credentials in use are never ambiguous.
+func (cfg AWSClientConfig) credentialsSource() (credentialsSource, error) {
+	var sources []credentialsSource
+
+	if cfg.AccessKeyID != "" || cfg.SecretAccessKey != "" {
+		if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+			return "", fmt.Errorf("both the AWS access key ID and the secret access key are required")
+		}
+
+		sources = append(sources, credentialsSourceStatic)
+	} else if cfg.SessionToken != "" {
+		return "", fmt.Errorf("the AWS session token requires an access key ID and a secret access key")
+	}
+
+	if len(cfg.SharedConfigFiles) > 0 || len(cfg.SharedCredentialsFiles) > 0 {
+		sources = append(sources, credentialsSourceProfile)
+	} else if cfg.Profile != "" {
+		return "", fmt.Errorf("the AWS profile %s requires a shared config or credentials file", cfg.Profile)
+	}
+
+	if cfg.WebIdentityRoleARN != "" || cfg.WebIdentityToken != "" {
+		if cfg.WebIdentityRoleARN == "" || cfg.WebIdentityToken == "" {
+			return "", fmt.Errorf("both the web identity token and the role ARN are required")
+		}
+
+		sources = append(sources, credentialsSourceWebIdentity)
+	}
+
+	switch len(sources) {
+	case 0:
+		return "", fmt.Errorf("AWS credentials are required: static keys, a shared config or credentials " +
+			"file with a profile, or a web identity token with a role ARN")
+	case 1:
+		return sources[0], nil
+	default:
+		return "", fmt.Errorf("only one source of AWS credentials can be set, got %v", sources)
+	}
+}
+
+// credentialsLoadOptions returns the options to load the AWS configuration with the credentials of the
+// given source. Web identity credentials need an STS client, so they're set once the configuration is loaded.
+func (cfg AWSClientConfig) credentialsLoadOptions(source credentialsSource) []func(*config.LoadOptions) error {
+	switch source {
+	case credentialsSourceStatic:
+		return []func(*config.LoadOptions) error{
+			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+				cfg.AccessKeyID,
+				cfg.SecretAccessKey,
+				cfg.SessionToken, // Include session token if provided
+			)),
+		}
+	case credentialsSourceProfile:
+		profile := cfg.Profile
+		if profile == "" {
+			profile = defaultSharedConfigProfile
+		}
+
+		// The mounted files replace the default ones (~/.aws/config and ~/.aws/credentials)
+		return []func(*config.LoadOptions) error{
+			config.WithSharedConfigFiles(cfg.SharedConfigFiles),
+			config.WithSharedCredentialsFiles(cfg.SharedCredentialsFiles),
+			config.WithSharedConfigProfile(profile),
+		}
+	default:
+		return nil
+	}
+}
+
+// withWebIdentityCredentials sets the credentials of the role assumed with the web identity token
+// (sts:AssumeRoleWithWebIdentity) in the loaded AWS configuration.
+func (cfg AWSClientConfig) withWebIdentityCredentials(awsCfg aws.Config) aws.Config {
+	sessionName := cfg.RoleSessionName
+	if sessionName == "" {
+		sessionName = defaultAssumeRoleSessionName
+	}
+
+	provider := stscreds.NewWebIdentityRoleProvider(
+		sts.NewFromConfig(awsCfg),
+		cfg.WebIdentityRoleARN,
+		identityToken(cfg.WebIdentityToken),
+		func(o *stscreds.WebIdentityRoleOptions) {
+			o.RoleSessionName = sessionName
+		})
+
+	awsCfg.Credentials = aws.NewCredentialsCache(provider)
+
+	return awsCfg
+}
+
+// writeSharedFile writes the contents of a mounted shared config or credentials file to the given
+// private directory, since the AWS SDK reads these files from disk. It returns the path of the file.
+func writeSharedFile(ctx context.Context, file *dagger.File, dir, name string) (string, error) {
+	contents, err := file.Contents(ctx)
+	if err != nil {
+		return "", WrapErrorf(err, "failed to read the AWS shared %s file", name)
+	}
+
+	path := filepath.Join(dir, name)
+
+	// Only the current user can read it, as the AWS CLI does with the files it writes.
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		return "", WrapErrorf(err, "failed to write the AWS shared %s file", name)
+	}
+
+	return path, nil
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/mail"
 	"regexp"
+	"strings"
 
 	"github.com/Excoriate/daggerverse/aws-tag-inspector/internal/dagger"
 	"gopkg.in/yaml.v3"
@@ -43,6 +45,10 @@ func (l *configLoader) loadConfig(ctx context.Context, cfg *dagger.File) (*inspe
 		return nil, WrapError(err, "failed to compile pattern rules")
 	}
 
+	if err := l.compileExclusionPatterns(parsedCfg); err != nil {
+		return nil, WrapError(err, "failed to compile exclusion patterns")
+	}
+
 	l.config = parsedCfg
 	return parsedCfg, nil
 }
@@ -79,6 +85,21 @@ func (l *configLoader) validateConfig(config *inspectorConfig) error {
 		return fmt.Errorf("notifications validation failed: %w", err)
 	}
 
+	// Validate accounts
+	if err := l.validateAccounts(config.Accounts); err != nil {
+		return fmt.Errorf("accounts validation failed: %w", err)
+	}
+
+	// Validate conditional tagging rules
+	if err := l.validateTagRules(config.Rules); err != nil {
+		return fmt.Errorf("tagging rules validation failed: %w", err)
+	}
+
+	// Validate remediation settings
+	if err := l.validateRemediation(config.Remediation); err != nil {
+		return fmt.Errorf("remediation validation failed: %w", err)
+	}
+
 	return nil
 }
 
@@ -101,6 +122,12 @@ func (l *configLoader) validateGlobalConfig(global *globalConfig) error {
 // validateResourceConfigs validates resource-specific configurations
 func (l *configLoader) validateResourceConfigs(resources map[string]resourceConfig) error {
 	for resourceType, resourceConfig := range resources {
+		// Validate resource type: a dedicated scanner (e.g. s3, ec2), or a tagging API type name
+		if !isTaggingAPIResourceType(resourceType) {
+			return fmt.Errorf("invalid resource type %s, expected a service or a tagging API type "+
+				"name such as lambda:function", resourceType)
+		}
+
 		// Validate batch size
 		if resourceConfig.BatchSize != nil && *resourceConfig.BatchSize <= 0 {
 			return fmt.Errorf("invalid batch size for resource type %s", resourceType)
@@ -113,7 +140,7 @@ func (l *configLoader) validateResourceConfigs(resources map[string]resourceConf
 
 		// Validate excluded resource patterns
 		for _, excluded := range resourceConfig.ExcludedResources {
-			if _, err := regexp.Compile(excluded.Pattern); err != nil {
+			if _, err := excluded.compile(); err != nil {
 				return fmt.Errorf("invalid exclusion pattern %s for resource type %s: %w",
 					excluded.Pattern, resourceType, err)
 			}
@@ -155,16 +182,20 @@ func (l *configLoader) validateTagValidationRules(tagValidation tagValidation) e
 func (l *configLoader) validateNotifications(notifications notificationConfig) error {
 	// Validate Slack notifications
 	if notifications.Slack.Enabled {
-		if len(notifications.Slack.Channels) == 0 {
-			return fmt.Errorf("Slack notifications enabled but no channels specified")
-		}
-
 		// Validate each channel name
 		for _, channel := range notifications.Slack.Channels {
 			if channel == "" {
 				return fmt.Errorf("empty Slack channel name found")
 			}
 		}
+
+		if _, err := newSlackTemplate(notifications.Slack.Template); err != nil {
+			return fmt.Errorf("invalid Slack template: %w", err)
+		}
+	}
+
+	if notifications.TopOffenders < 0 {
+		return fmt.Errorf("the number of top offenders cannot be negative")
 	}
 
 	// Validate Email notifications
@@ -195,6 +226,146 @@ func (l *configLoader) validateNotifications(notifications notificationConfig) e
 				return fmt.Errorf("invalid email notification frequency: %s", notifications.Email.Frequency)
 			}
 		}
+
+		if err := l.validateEmailDelivery(notifications.Email); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateEmailDelivery validates the sender, the format, the templates and the SMTP server of the
+// email notifications. The SMTP host is only required to send them, so a configuration without it is valid.
+func (l *configLoader) validateEmailDelivery(email emailNotificationConfig) error {
+	if email.From != "" {
+		if _, err := mail.ParseAddress(email.From); err != nil {
+			return fmt.Errorf("invalid email sender %s: %w", email.From, err)
+		}
+	}
+
+	if email.Format != "" && email.Format != emailFormatHTML && email.Format != emailFormatText {
+		return fmt.Errorf("invalid email format %s, expected %s or %s", email.Format, emailFormatHTML, emailFormatText)
+	}
+
+	if email.SMTP.Port < 0 || email.SMTP.Port > maxPort {
+		return fmt.Errorf("invalid SMTP port: %d", email.SMTP.Port)
+	}
+
+	if _, err := newEmailTemplates(email); err != nil {
+		return fmt.Errorf("invalid email template: %w", err)
+	}
+
+	return nil
+}
+
+// validateAccounts validates the member accounts and the role to assume in each of them
+func (l *configLoader) validateAccounts(accounts accountsConfig) error {
+	if len(accounts.IDs) == 0 {
+		return nil
+	}
+
+	if accounts.RoleName == "" {
+		return fmt.Errorf("role name is required to scan accounts %v", accounts.IDs)
+	}
+
+	seen := make(map[string]bool, len(accounts.IDs))
+	for _, accountID := range accounts.IDs {
+		if !isValidAWSAccountID(accountID) {
+			return fmt.Errorf("invalid AWS account ID %s, expected 12 digits", accountID)
+		}
+
+		if seen[accountID] {
+			return fmt.Errorf("duplicated AWS account ID: %s", accountID)
+		}
+
+		seen[accountID] = true
+	}
+
+	return nil
+}
+
+// validateTagRules validates the conditional tagging rules. Errors cite the ID (or the position) of the rule.
+func (l *configLoader) validateTagRules(rules []tagRule) error {
+	seen := make(map[string]bool, len(rules))
+
+	for idx, rule := range rules {
+		if !ruleIDRegex.MatchString(rule.ID) {
+			return fmt.Errorf("invalid or missing ID %q in rule #%d, expected letters, digits, '.', '_' or '-'",
+				rule.ID, idx+1)
+		}
+
+		if seen[rule.ID] {
+			return fmt.Errorf("duplicated rule ID: %s", rule.ID)
+		}
+
+		seen[rule.ID] = true
+
+		if err := l.validateTagRule(rule); err != nil {
+			return fmt.Errorf("invalid rule %s: %w", rule.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// validateTagRule validates the condition and the requirements of a conditional tagging rule
+func (l *configLoader) validateTagRule(rule tagRule) error {
+	for key, values := range rule.When.Tags {
+		if key == "" || len(values) == 0 {
+			return fmt.Errorf("the tags of the condition need a key and at least one value")
+		}
+	}
+
+	for _, key := range rule.When.TagsPresent {
+		if key == "" {
+			return fmt.Errorf("empty tag key in the tags_present of the condition")
+		}
+	}
+
+	require := rule.Require
+	if len(require.Tags) == 0 && require.OneOf == nil && require.KeyCase == "" {
+		return fmt.Errorf("no requirement specified, expected tags, one_of or key_case")
+	}
+
+	for _, key := range require.Tags {
+		if key == "" {
+			return fmt.Errorf("empty required tag found")
+		}
+	}
+
+	if oneOf := require.OneOf; oneOf != nil {
+		if oneOf.Tag == "" {
+			return fmt.Errorf("one_of requires the tag whose value is restricted")
+		}
+
+		if (len(oneOf.Values) == 0) == (oneOf.ValuesFromTag == "") {
+			return fmt.Errorf("one_of requires either values or values_from_tag")
+		}
+	}
+
+	if require.KeyCase != "" {
+		if _, exists := keyCasePatterns[require.KeyCase]; !exists {
+			return fmt.Errorf("unknown key_case %s, expected PascalCase, camelCase, snake_case, "+
+				"kebab-case, lowercase or UPPERCASE", require.KeyCase)
+		}
+	}
+
+	return nil
+}
+
+// validateRemediation validates the default values of the remediation plans. Tags prefixed with
+// "aws:" are reserved by AWS, so they can't be set.
+func (l *configLoader) validateRemediation(remediation remediationConfig) error {
+	for key := range remediation.DefaultValues {
+		if key == "" {
+			return fmt.Errorf("empty tag key found in the default values")
+		}
+
+		if strings.HasPrefix(key, awsReservedTagPrefix) {
+			return fmt.Errorf("invalid default value for tag %s, tags prefixed with %s are reserved by AWS",
+				key, awsReservedTagPrefix)
+		}
 	}
 
 	return nil
@@ -215,6 +386,23 @@ func (l *configLoader) compilePatternRules(config *inspectorConfig) error {
 	return nil
 }
 
+// compileExclusionPatterns pre-compiles the patterns of the excluded resources
+func (l *configLoader) compileExclusionPatterns(config *inspectorConfig) error {
+	for resourceType, resourceConfig := range config.Resources {
+		// The slice shares its backing array with the map value, so the compiled patterns are kept
+		for idx := range resourceConfig.ExcludedResources {
+			compiled, err := resourceConfig.ExcludedResources[idx].compile()
+			if err != nil {
+				return fmt.Errorf("invalid exclusion pattern for resource type %s: %w", resourceType, err)
+			}
+
+			resourceConfig.ExcludedResources[idx].regex = compiled
+		}
+	}
+
+	return nil
+}
+
 // getTagCriteria generates TagCriteria for a specific resource type
 func (l *configLoader) getTagCriteria(resourceType string) TagCriteria {
 	if l.config == nil {
@@ -262,8 +450,8 @@ func (l *configLoader) isResourceExcluded(resourceType, resourceID string) (bool
 	}
 
 	for _, excluded := range resourceConfig.ExcludedResources {
-		if matched, _ := regexp.MatchString(excluded.Pattern, resourceID); matched {
-			return true, excluded.Reason
+		if excluded.matches(resourceID) {
+			return true, excluded.reason()
 		}
 	}
 
@@ -14,6 +14,9 @@ type inspectorConfig struct {
 	ComplianceLevels map[string]complianceLevel `yaml:"compliance_levels"`
 	TagValidation    tagValidation              `yaml:"tag_validation"`
 	Notifications    notificationConfig         `yaml:"notifications"`
+	Accounts         accountsConfig             `yaml:"accounts"`
+	Rules            []tagRule                  `yaml:"rules"`
+	Remediation      remediationConfig          `yaml:"remediation"`
 }
 
 // globalConfig defines the default configuration settings that apply across all resources.
@@ -34,10 +37,22 @@ type resourceConfig struct {
 }
 
 // excludedResource defines a specific resource to be excluded from tag inspection,
-// with a pattern to match and a reason for exclusion.
+// with a pattern to match and a reason for exclusion. The pattern is a glob by default
+// (e.g. "terraform-state-*"), or a regular expression if match is "regex".
 type excludedResource struct {
 	Pattern string `yaml:"pattern"`
+	Match   string `yaml:"match"`
 	Reason  string `yaml:"reason"`
+	regex   *regexp.Regexp
+}
+
+// accountsConfig lists the member accounts to scan, and the role assumed in each of them with STS.
+// If no account is listed, only the account of the module's credentials is scanned.
+type accountsConfig struct {
+	IDs         []string `yaml:"ids"`
+	RoleName    string   `yaml:"role_name"`
+	ExternalID  string   `yaml:"external_id"`
+	SessionName string   `yaml:"session_name"`
 }
 
 // complianceLevel specifies the tag requirements for achieving a particular
@@ -55,27 +70,90 @@ type tagValidation struct {
 	compiledRules map[string]*regexp.Regexp
 }
 
+// tagRule is a conditional tagging rule: when the condition holds for a resource, its tags must
+// meet the requirements. Each issue found by the rule cites its ID.
+type tagRule struct {
+	ID            string          `yaml:"id"`
+	Description   string          `yaml:"description"`
+	ResourceTypes []string        `yaml:"resource_types"`
+	When          ruleCondition   `yaml:"when"`
+	Require       ruleRequirement `yaml:"require"`
+}
+
+// ruleCondition selects the resources a rule applies to. All its checks must hold, and an empty
+// condition applies the rule to every resource.
+type ruleCondition struct {
+	// Tags maps tag keys to the values (one or a list) the tag must have, e.g. Environment: [prod, production]
+	Tags map[string]ruleValues `yaml:"tags"`
+	// TagsPresent are the tag keys the resource must have, whatever their value
+	TagsPresent []string `yaml:"tags_present"`
+}
+
+// ruleRequirement is what a rule enforces on the tags of the resources it applies to.
+type ruleRequirement struct {
+	// Tags are the tag keys that are required
+	Tags []string `yaml:"tags"`
+	// OneOf restricts the value of a tag to a list of values
+	OneOf *oneOfRequirement `yaml:"one_of"`
+	// KeyCase is the casing every tag key must follow, e.g. PascalCase
+	KeyCase string `yaml:"key_case"`
+}
+
+// oneOfRequirement restricts the value of a tag to a static list of values, or to the values
+// held by another tag of the same resource (separated by the separator, "," by default).
+type oneOfRequirement struct {
+	Tag           string   `yaml:"tag"`
+	Values        []string `yaml:"values"`
+	ValuesFromTag string   `yaml:"values_from_tag"`
+	Separator     string   `yaml:"separator"`
+}
+
+// remediationConfig holds the settings of the remediation plans. The default values are the
+// values set on the resources that are missing a required tag, e.g. Owner: platform-team.
+type remediationConfig struct {
+	DefaultValues map[string]string `yaml:"default_values"`
+}
+
 // notificationConfig manages the notification settings for reporting
 // tag inspection results through different channels.
 type notificationConfig struct {
 	Slack     slackNotificationConfig `yaml:"slack"`
 	Email     emailNotificationConfig `yaml:"email"`
 	Frequency string                  `yaml:"frequency"`
+	// TopOffenders is the number of non-compliant resources (with the most issues) listed in the notifications
+	TopOffenders int `yaml:"top_offenders"`
 }
 
-// slackConfig defines the configuration for Slack notifications,
-// including whether they are enabled and which channels to use.
+// slackConfig defines the configuration for Slack notifications, including whether they are
+// enabled and the channel set in the payload, which only legacy webhooks honour.
+// The template is a Go template of the message, which overrides the default one.
 type slackNotificationConfig struct {
 	Enabled  bool              `yaml:"enabled"`
 	Channels map[string]string `yaml:"channels"`
+	Template string            `yaml:"template"`
 }
 
 // emailConfig specifies the email notification settings,
 // including whether email notifications are enabled and the list of recipients.
+// The subject and the template are Go templates, which override the default ones. The
+// email is sent as HTML (default) or as plain text, depending on its format.
 type emailNotificationConfig struct {
-	Enabled    bool     `yaml:"enabled"`
-	Recipients []string `yaml:"recipients"`
-	Frequency  string   `yaml:"frequency"`
+	Enabled    bool       `yaml:"enabled"`
+	Recipients []string   `yaml:"recipients"`
+	Frequency  string     `yaml:"frequency"`
+	From       string     `yaml:"from"`
+	Format     string     `yaml:"format"`
+	Subject    string     `yaml:"subject"`
+	Template   string     `yaml:"template"`
+	SMTP       smtpConfig `yaml:"smtp"`
+}
+
+// smtpConfig is the SMTP server the emails are sent through. The password isn't part of the
+// configuration, it's passed as a secret when notifying.
+type smtpConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
 }
 
 // TagCriteria defines the criteria for validating resource tags in AWS.
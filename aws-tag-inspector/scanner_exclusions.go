@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// exclusionMatchGlob matches the resources with a glob, where '*' matches any sequence of
+	// characters (including '/') and '?' matches a single character.
+	exclusionMatchGlob = "glob"
+	// exclusionMatchRegex matches the resources with a regular expression. It's the default.
+	exclusionMatchRegex = "regex"
+	// complianceTagExcluded is the compliance tag of the resources skipped by an exclusion.
+	complianceTagExcluded = "excluded"
+)
+
+// compile returns the regular expression of the exclusion pattern, according to its match type
+func (e excludedResource) compile() (*regexp.Regexp, error) {
+	if e.Pattern == "" {
+		return nil, fmt.Errorf("empty exclusion pattern")
+	}
+
+	switch e.Match {
+	case "", exclusionMatchRegex:
+		return regexp.Compile(e.Pattern)
+	case exclusionMatchGlob:
+		return regexp.Compile(globToRegex(e.Pattern))
+	default:
+		return nil, fmt.Errorf("invalid match type %s, expected %s or %s",
+			e.Match, exclusionMatchGlob, exclusionMatchRegex)
+	}
+}
+
+// matches checks if the resource identifier (e.g. its ID, name or ARN) matches the exclusion pattern
+func (e excludedResource) matches(resourceID string) bool {
+	if resourceID == "" {
+		return false
+	}
+
+	regex := e.regex
+	if regex == nil {
+		compiled, err := e.compile()
+		if err != nil {
+			return false
+		}
+
+		regex = compiled
+	}
+
+	return regex.MatchString(resourceID)
+}
+
+// reason returns the reason of the exclusion, or the pattern that matched if no reason is set
+func (e excludedResource) reason() string {
+	if e.Reason != "" {
+		return e.Reason
+	}
+
+	return fmt.Sprintf("Matches the exclusion pattern %s", e.Pattern)
+}
+
+// globToRegex converts a glob to an anchored regular expression
+func globToRegex(glob string) string {
+	quoted := regexp.QuoteMeta(glob)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+
+	return "^" + quoted + "$"
+}
+
+// newExcludedResult returns the scan result of a resource skipped by an exclusion, with its reason.
+// Its tags aren't scanned.
+func newExcludedResult(resource BaseResource, reason string) ScanResult {
+	return ScanResult{
+		ResourceType:    resource.ResourceType,
+		ResourceID:      resource.ResourceID,
+		ARN:             resource.ARN,
+		Region:          resource.Region,
+		Tags:            resource.GetTags(),
+		Metadata:        resource.Metadata,
+		ComplianceTag:   complianceTagExcluded,
+		ExclusionReason: reason,
+	}
+}
@@ -0,0 +1,563 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Excoriate/daggerverse/aws-tag-inspector/internal/dagger"
+)
+
+const (
+	emailFormatHTML = "html"
+	emailFormatText = "text"
+	// defaultTopOffenders is the number of top offenders listed in the notifications, if not configured.
+	defaultTopOffenders = 5
+	defaultSMTPPort     = 587
+	maxPort             = 65535
+	// notificationTimeout is the timeout of each Slack request and SMTP session.
+	notificationTimeout = 30 * time.Second
+	// slackErrorBodyLimit is the number of bytes of a failed Slack response reported in the error.
+	slackErrorBodyLimit = 512
+)
+
+const defaultSlackTemplate = `*AWS tag compliance report*
+{{.Summary.Compliance.NonCompliant}} of {{.Summary.TotalResources}} resources are non-compliant ` +
+	`({{.CompliancePercent}}% compliant){{with .Summary.Excluded}}, {{len .}} excluded{{end}}.
+{{- if .TopOffenders}}
+
+*Top offenders*
+{{- range .TopOffenders}}
+• ` + "`{{escape .ResourceID}}`" + ` ({{.ResourceType}}, {{.Region}}{{with .AccountID}}, {{.}}{{end}}): ` +
+	`{{len .Issues}} issue(s){{if .Issues}}, e.g. {{escape (index .Issues 0)}}{{end}}
+{{- end}}
+{{- end}}
+`
+
+const defaultEmailSubject = `AWS tag compliance: {{.Summary.Compliance.NonCompliant}} non-compliant resource(s) ` +
+	`of {{.Summary.TotalResources}}`
+
+const defaultEmailText = `AWS tag compliance report
+
+Resources scanned: {{.Summary.TotalResources}}
+Compliant: {{.Summary.Compliance.Compliant}} ({{.CompliancePercent}}%)
+Non-compliant: {{.Summary.Compliance.NonCompliant}}
+Excluded: {{len .Summary.Excluded}}
+{{- if .TopOffenders}}
+
+Top offenders:
+{{- range .TopOffenders}}
+- {{.ResourceID}} ({{.ResourceType}}, {{.Region}}{{with .AccountID}}, {{.}}{{end}})
+{{- range .Issues}}
+    * {{.}}
+{{- end}}
+{{- end}}
+{{- end}}
+`
+
+const defaultEmailHTML = `<!DOCTYPE html>
+<html lang="en">
+<body style="font-family: sans-serif; color: #1f2328;">
+<h2>AWS tag compliance report</h2>
+<table cellpadding="6" style="border-collapse: collapse;">
+  <tr><td>Resources scanned</td><td><strong>{{.Summary.TotalResources}}</strong></td></tr>
+  <tr><td>Compliant</td><td><strong>{{.Summary.Compliance.Compliant}}</strong> ({{.CompliancePercent}}%)</td></tr>
+  <tr><td>Non-compliant</td><td><strong>{{.Summary.Compliance.NonCompliant}}</strong></td></tr>
+  <tr><td>Excluded</td><td><strong>{{len .Summary.Excluded}}</strong></td></tr>
+</table>
+{{- if .TopOffenders}}
+<h3>Top offenders</h3>
+<table cellpadding="6" border="1" style="border-collapse: collapse;">
+  <tr><th>Resource</th><th>Type</th><th>Region</th><th>Issues</th></tr>
+  {{- range .TopOffenders}}
+  <tr>
+    <td>{{.ResourceID}}</td>
+    <td>{{.ResourceType}}</td>
+    <td>{{.Region}}</td>
+    <td><ul>{{range .Issues}}<li>{{.}}</li>{{end}}</ul></td>
+  </tr>
+  {{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`
+
+// notificationData is the data of the notification templates.
+type notificationData struct {
+	Summary scanSummary
+	// CompliancePercent is the share of compliant resources, e.g. "87.5"
+	CompliancePercent string
+	// TopOffenders are the non-compliant resources with the most issues
+	TopOffenders []ScanResult
+}
+
+// templateExecutor is a parsed text or HTML template.
+type templateExecutor interface {
+	Execute(wr io.Writer, data any) error
+}
+
+// emailTemplates are the parsed templates of the subject and the body of the emails.
+type emailTemplates struct {
+	subject *template.Template
+	body    templateExecutor
+	format  string
+}
+
+// slackMessage is the payload posted to a Slack incoming webhook.
+type slackMessage struct {
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text"`
+}
+
+// Notify sends the summary of a scan, and its top offenders, to the notification channels enabled in
+// the configuration: the Slack incoming webhook and the email recipients (through an SMTP server).
+//
+// The messages are rendered with the templates of the configuration, or with the default ones. The
+// Slack message is posted once, to the channel the webhook was created for; a single configured
+// channel is set in the payload, which only legacy webhooks honour. The emails are sent
+// as HTML or plain text, with STARTTLS and PLAIN authentication if the server supports them.
+// Notifications are sent when Notify is called, so the frequency of the configuration is left to
+// the scheduler that runs it.
+//
+// Parameters:
+//   - ctx: Optional context for controlling the notification's lifecycle and timeout.
+//   - scanResults: The JSON report of a previous scan. If not set, the resources are scanned first.
+//   - slackWebhookUrl: The URL of the Slack incoming webhook. Required if Slack notifications are enabled.
+//   - smtpPassword: The password of the SMTP user. Required if the SMTP server needs authentication.
+//
+// Returns:
+//   - A message listing the webhook and recipients that were notified.
+//   - An error if no channel is enabled, or if any notification can't be delivered.
+func (m *AwsTagInspector) Notify(
+	ctx context.Context,
+	// scanResults is the JSON report of a previous scan. If not set, the resources are scanned first.
+	// +optional
+	scanResults *dagger.File,
+	// slackWebhookUrl is the URL of the Slack incoming webhook the messages are posted to.
+	// +optional
+	slackWebhookUrl *dagger.Secret,
+	// smtpPassword is the password of the SMTP user of the configuration.
+	// +optional
+	smtpPassword *dagger.Secret,
+) (string, error) {
+	if m.Cfg == nil {
+		return "", Errorf("configuration is required for notifications")
+	}
+
+	notifications := m.Cfg.Notifications
+	if !notifications.Slack.Enabled && !notifications.Email.Enabled {
+		return "", Errorf("no notification channel is enabled in the configuration")
+	}
+
+	results, err := m.loadScanResults(ctx, scanResults)
+	if err != nil {
+		return "", err
+	}
+
+	data := newNotificationData(results, notifications.TopOffenders)
+
+	var (
+		delivered    []string
+		deliveryErrs []error
+		httpClient   = &http.Client{Timeout: notificationTimeout}
+	)
+
+	if notifications.Slack.Enabled {
+		webhookURL, err := plaintextOrEmpty(ctx, slackWebhookUrl)
+		if err != nil {
+			return "", WrapError(err, "failed to read the Slack webhook URL")
+		}
+
+		if err := sendSlackNotification(ctx, httpClient, webhookURL, notifications.Slack, data); err != nil {
+			deliveryErrs = append(deliveryErrs, err)
+		} else {
+			delivered = append(delivered, "the Slack webhook")
+		}
+	}
+
+	if notifications.Email.Enabled {
+		password, err := plaintextOrEmpty(ctx, smtpPassword)
+		if err != nil {
+			return "", WrapError(err, "failed to read the SMTP password")
+		}
+
+		if err := sendEmailNotification(ctx, notifications.Email, password, data); err != nil {
+			deliveryErrs = append(deliveryErrs, err)
+		} else {
+			delivered = append(delivered, fmt.Sprintf("%d email recipient(s)", len(notifications.Email.Recipients)))
+		}
+	}
+
+	if err := errors.Join(deliveryErrs...); err != nil {
+		return "", WrapError(err, "failed to deliver the notifications")
+	}
+
+	return "notified " + strings.Join(delivered, " and "), nil
+}
+
+// plaintextOrEmpty returns the trimmed value of an optional secret, or an empty string if it's not set
+func plaintextOrEmpty(ctx context.Context, secret *dagger.Secret) (string, error) {
+	if secret == nil {
+		return "", nil
+	}
+
+	value, err := secret.Plaintext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(value), nil
+}
+
+// newNotificationData summarizes the scan results, and picks the top offenders: the non-compliant
+// resources with the most issues
+func newNotificationData(results []ScanResult, topOffenders int) notificationData {
+	if topOffenders == 0 {
+		topOffenders = defaultTopOffenders
+	}
+
+	summary := summarizeResults(results)
+
+	compliancePercent := 100.0
+	if summary.TotalResources > 0 {
+		compliancePercent = float64(summary.Compliance.Compliant) * 100 / float64(summary.TotalResources)
+	}
+
+	var offenders []ScanResult
+
+	for _, result := range sortedResults(results) {
+		if result.ComplianceTag == "non-compliant" {
+			offenders = append(offenders, result)
+		}
+	}
+
+	sort.SliceStable(offenders, func(i, j int) bool {
+		return len(offenders[i].Issues) > len(offenders[j].Issues)
+	})
+
+	if len(offenders) > topOffenders {
+		offenders = offenders[:topOffenders]
+	}
+
+	return notificationData{
+		Summary:           summary,
+		CompliancePercent: strconv.FormatFloat(compliancePercent, 'f', 1, 64),
+		TopOffenders:      offenders,
+	}
+}
+
+// newSlackTemplate parses the Slack template, or the default one if it's empty
+func newSlackTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultSlackTemplate
+	}
+
+	return template.New("slack").Funcs(template.FuncMap{
+		"escape": escapeSlackText,
+		"join":   strings.Join,
+	}).Parse(text)
+}
+
+// newEmailTemplates parses the subject and body templates of the emails, or the default ones of their
+// format. HTML bodies are parsed as HTML templates, so the values are escaped.
+func newEmailTemplates(email emailNotificationConfig) (emailTemplates, error) {
+	templates := emailTemplates{format: email.Format}
+	if templates.format == "" {
+		templates.format = emailFormatHTML
+	}
+
+	subject := email.Subject
+	if subject == "" {
+		subject = defaultEmailSubject
+	}
+
+	subjectTemplate, err := template.New("subject").Parse(subject)
+	if err != nil {
+		return emailTemplates{}, fmt.Errorf("failed to parse the subject: %w", err)
+	}
+
+	templates.subject = subjectTemplate
+
+	body := email.Template
+	funcs := template.FuncMap{"join": strings.Join}
+
+	if templates.format == emailFormatText {
+		if body == "" {
+			body = defaultEmailText
+		}
+
+		templates.body, err = template.New("body").Funcs(funcs).Parse(body)
+	} else {
+		if body == "" {
+			body = defaultEmailHTML
+		}
+
+		templates.body, err = htmltemplate.New("body").Funcs(htmltemplate.FuncMap(funcs)).Parse(body)
+	}
+
+	if err != nil {
+		return emailTemplates{}, fmt.Errorf("failed to parse the body: %w", err)
+	}
+
+	return templates, nil
+}
+
+// sendSlackNotification posts the message once to the webhook. Current webhooks are bound to a
+// channel and ignore the one of the payload, so posting once per channel would only duplicate the
+// message: the channel is set only if a single one is configured, for the legacy webhooks.
+func sendSlackNotification(
+	ctx context.Context,
+	httpClient *http.Client,
+	webhookURL string,
+	slack slackNotificationConfig,
+	data notificationData,
+) error {
+	if webhookURL == "" {
+		return fmt.Errorf("slack notifications are enabled, but no webhook URL was passed")
+	}
+
+	slackTemplate, err := newSlackTemplate(slack.Template)
+	if err != nil {
+		return fmt.Errorf("invalid Slack template: %w", err)
+	}
+
+	var text bytes.Buffer
+	if err := slackTemplate.Execute(&text, data); err != nil {
+		return fmt.Errorf("failed to render the Slack message: %w", err)
+	}
+
+	// The same channel can be configured under several names, it still counts as a single one
+	channels := make([]string, 0, len(slack.Channels))
+	for _, channel := range slack.Channels {
+		if !slices.Contains(channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+
+	message := slackMessage{Text: text.String()}
+	if len(channels) == 1 {
+		message.Channel = channels[0]
+	}
+
+	if err := postSlackMessage(ctx, httpClient, webhookURL, message); err != nil {
+		return fmt.Errorf("failed to notify Slack: %w", err)
+	}
+
+	return nil
+}
+
+// postSlackMessage posts a message to a Slack incoming webhook
+func postSlackMessage(ctx context.Context, httpClient *http.Client, webhookURL string, message slackMessage) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the Slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		// The error isn't wrapped, since it could contain the webhook URL.
+		return fmt.Errorf("invalid Slack webhook URL")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// The URL of the error is dropped, since it's the webhook URL, which is a secret.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return fmt.Errorf("failed to post the Slack message: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, slackErrorBodyLimit))
+
+		return fmt.Errorf("slack webhook returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// escapeSlackText escapes the characters that Slack interprets as control sequences in messages
+func escapeSlackText(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// sendEmailNotification renders the email, and sends it to every recipient through the SMTP server
+func sendEmailNotification(
+	ctx context.Context,
+	email emailNotificationConfig,
+	password string,
+	data notificationData,
+) error {
+	if email.SMTP.Host == "" {
+		return fmt.Errorf("email notifications are enabled, but no SMTP host is configured")
+	}
+
+	if email.From == "" {
+		return fmt.Errorf("email notifications are enabled, but no sender (from) is configured")
+	}
+
+	templates, err := newEmailTemplates(email)
+	if err != nil {
+		return fmt.Errorf("invalid email template: %w", err)
+	}
+
+	var subject, body bytes.Buffer
+
+	if err := templates.subject.Execute(&subject, data); err != nil {
+		return fmt.Errorf("failed to render the email subject: %w", err)
+	}
+
+	if err := templates.body.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to render the email body: %w", err)
+	}
+
+	message, err := newEmailMessage(email.From, email.Recipients,
+		strings.TrimSpace(subject.String()), body.String(), templates.format)
+	if err != nil {
+		return err
+	}
+
+	if err := sendMail(ctx, email.SMTP, password, email.From, email.Recipients, message); err != nil {
+		return fmt.Errorf("failed to send the email through %s: %w", email.SMTP.Host, err)
+	}
+
+	return nil
+}
+
+// newEmailMessage builds a MIME message, with a quoted-printable body, so long lines and non-ASCII
+// characters are safe
+func newEmailMessage(from string, recipients []string, subject, body, format string) ([]byte, error) {
+	contentType := "text/html"
+	if format == emailFormatText {
+		contentType = "text/plain"
+	}
+
+	var message bytes.Buffer
+
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: %s; charset=\"utf-8\"\r\n", contentType)
+	message.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	encoder := quotedprintable.NewWriter(&message)
+	if _, err := encoder.Write([]byte(body)); err != nil {
+		return nil, fmt.Errorf("failed to encode the email body: %w", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode the email body: %w", err)
+	}
+
+	return message.Bytes(), nil
+}
+
+// sendMail sends a message through the SMTP server. It's smtp.SendMail, bound to the context and to
+// the notification timeout, so an unresponsive server doesn't block the notification forever.
+func sendMail(
+	ctx context.Context,
+	server smtpConfig,
+	password, from string,
+	recipients []string,
+	message []byte,
+) error {
+	port := server.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+
+	dialer := net.Dialer{Timeout: notificationTimeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(server.Host, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(notificationTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, server.Host)
+	if err != nil {
+		conn.Close()
+
+		return err
+	}
+
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: server.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+
+	if server.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("the SMTP server doesn't support authentication")
+		}
+
+		if err := client.Auth(smtp.PlainAuth("", server.Username, password, server.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", recipient, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
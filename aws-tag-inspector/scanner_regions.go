@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// allRegionsKeyword scans every region enabled in the account, discovered with ec2:DescribeRegions.
+	allRegionsKeyword = "all"
+	// maxConcurrentRegions is the maximum number of regions scanned at the same time.
+	maxConcurrentRegions = 5
+)
+
+// globalResourceTypes are the resource types whose API lists the resources of every region
+// (e.g. ListBuckets), so they're scanned once instead of once per region.
+var globalResourceTypes = map[string]bool{
+	"s3": true,
+}
+
+// resolveRegions returns the regions to scan with the given client. If no region is configured, the
+// region of the client is used. The "all" keyword is resolved to the regions enabled in its account.
+func (m *AwsTagInspector) resolveRegions(ctx context.Context, awsClient *AWSClient) ([]string, error) {
+	if awsClient == nil {
+		return nil, Errorf("AWS client is not initialized")
+	}
+
+	if len(m.Regions) == 0 {
+		return []string{awsClient.Region()}, nil
+	}
+
+	seen := make(map[string]bool)
+
+	var regions []string
+
+	for _, region := range m.Regions {
+		if region != allRegionsKeyword {
+			if !isValidAWSRegion(region) {
+				return nil, Errorf("invalid AWS region format: %s", region)
+			}
+
+			if !seen[region] {
+				seen[region] = true
+				regions = append(regions, region)
+			}
+
+			continue
+		}
+
+		enabledRegions, err := discoverEnabledRegions(ctx, awsClient)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, enabledRegion := range enabledRegions {
+			if !seen[enabledRegion] {
+				seen[enabledRegion] = true
+				regions = append(regions, enabledRegion)
+			}
+		}
+	}
+
+	return regions, nil
+}
+
+// discoverEnabledRegions returns the regions enabled in the account, sorted by name
+func discoverEnabledRegions(ctx context.Context, awsClient *AWSClient) ([]string, error) {
+	client, err := awsClient.GetEC2Client()
+	if err != nil {
+		return nil, WrapError(err, "failed to initialize EC2 client to discover the regions")
+	}
+
+	// Without AllRegions, only the regions enabled in the account are returned.
+	output, err := client.DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, WrapError(err, "failed to discover the enabled regions")
+	}
+
+	regions := make([]string, 0, len(output.Regions))
+	for _, region := range output.Regions {
+		if name := aws.ToString(region.RegionName); name != "" {
+			regions = append(regions, name)
+		}
+	}
+
+	sort.Strings(regions)
+
+	return regions, nil
+}
+
+// scanRegions runs the configured scanners in every region concurrently, with the credentials of the
+// given client, and merges the results. Global resource types are scanned in the first region only,
+// and resources reported by more than one region (same ARN) are kept once.
+func (m *AwsTagInspector) scanRegions(
+	ctx context.Context,
+	awsClient *AWSClient,
+	regions []string,
+) ([]ScanResult, error) {
+	regionResults := make([][]ScanResult, len(regions))
+
+	scanners, scannersCtx := errgroup.WithContext(ctx)
+	scanners.SetLimit(maxConcurrentRegions)
+
+	for idx, region := range regions {
+		scanners.Go(func() error {
+			regionalClient, err := awsClient.ForRegion(region)
+			if err != nil {
+				return WrapError(err, fmt.Sprintf("failed to create AWS client for region %s", region))
+			}
+
+			results, err := m.scanRegion(scannersCtx, regionalClient, idx == 0)
+			if err != nil {
+				return WrapError(err, fmt.Sprintf("failed to scan region %s", region))
+			}
+
+			// Each region writes to its own index, so no lock is needed.
+			regionResults[idx] = results
+
+			return nil
+		})
+	}
+
+	if err := scanners.Wait(); err != nil {
+		return nil, err
+	}
+
+	seenARNs := make(map[string]bool)
+
+	var allResults []ScanResult
+
+	for _, results := range regionResults {
+		for _, result := range results {
+			if result.ARN != "" && seenARNs[result.ARN] {
+				continue
+			}
+
+			seenARNs[result.ARN] = true
+			allResults = append(allResults, result)
+		}
+	}
+
+	return allResults, nil
+}
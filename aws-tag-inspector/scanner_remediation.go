@@ -0,0 +1,484 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Excoriate/daggerverse/aws-tag-inspector/internal/dagger"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	remediationPlanFileName    = "remediation-plan.json"
+	remediationResultsFileName = "remediation-results.json"
+	// maxConcurrentRemediations is the number of resources tagged at the same time, per account and region.
+	maxConcurrentRemediations = 10
+	tagChangeAdd              = "add"
+	tagChangeUpdate           = "update"
+	remediationStatusPlanned  = "planned"
+	remediationStatusApplied  = "applied"
+	remediationStatusFailed   = "failed"
+	// remediationStatusUnresolved is the status of the resources whose missing tags have no default value,
+	// so they can only be fixed by hand.
+	remediationStatusUnresolved = "unresolved"
+	defaultValuesSource         = "remediation.default_values"
+)
+
+// tagChange is a change to the tags of a resource, and the configuration it comes from.
+type tagChange struct {
+	Key          string `json:"key"`
+	Action       string `json:"action"`
+	Value        string `json:"value"`
+	CurrentValue string `json:"current_value,omitempty"`
+	Source       string `json:"source"`
+}
+
+// resourceRemediation holds the tag changes planned for a resource and, once applied, their outcome.
+// The unresolved tags are the required tags that have no default value.
+type resourceRemediation struct {
+	ResourceType string      `json:"resource_type"`
+	ResourceID   string      `json:"resource_id"`
+	ARN          string      `json:"arn"`
+	Region       string      `json:"region"`
+	AccountID    string      `json:"account_id,omitempty"`
+	Changes      []tagChange `json:"changes"`
+	Unresolved   []string    `json:"unresolved,omitempty"`
+	Status       string      `json:"status"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// remediationPlan is the reviewable plan of the tag changes, with the outcome of each resource once applied.
+type remediationPlan struct {
+	DryRun  bool `json:"dry_run"`
+	Summary struct {
+		Resources  int `json:"resources"`
+		Changes    int `json:"changes"`
+		Applied    int `json:"applied"`
+		Failed     int `json:"failed"`
+		Unresolved int `json:"unresolved"`
+	} `json:"summary"`
+	Resources []resourceRemediation `json:"resources"`
+}
+
+// Remediate computes the tag changes that make the non-compliant resources compliant, and returns
+// them as a reviewable plan.
+//
+// For each resource, the plan sets the specific_tags of its criteria and of its compliance level
+// that are missing or have a wrong value, and adds the missing required tags that have a value in
+// the remediation.default_values of the configuration. The required tags without a default value
+// are reported as unresolved, to be fixed by hand. Excluded resources are never changed.
+//
+// By default it's a dry run. With apply, the plan is applied with the tagging API of each service
+// (EC2 CreateTags, S3 PutBucketTagging, or the Resource Groups Tagging API), and the outcome of each
+// resource is recorded in the plan. A failing resource doesn't stop the others.
+//
+// Parameters:
+//   - ctx: Optional context for controlling the remediation's lifecycle and timeout.
+//   - scanResults: The JSON report of a previous scan. If not set, the resources are scanned first.
+//   - apply: Whether to apply the plan. Default is false (dry run).
+//
+// Returns:
+//   - A Dagger file containing the plan (remediation-plan.json), or its outcome if applied
+//     (remediation-results.json).
+//   - An error if the resources can't be scanned, or the plan can't be computed.
+func (m *AwsTagInspector) Remediate(
+	ctx context.Context,
+	// scanResults is the JSON report of a previous scan. If not set, the resources are scanned first.
+	// +optional
+	scanResults *dagger.File,
+	// apply is whether to apply the plan. Default is false, which only returns the plan.
+	// +optional
+	apply bool,
+) (*dagger.File, error) {
+	if m.Cfg == nil {
+		return nil, Errorf("configuration is required for remediation")
+	}
+
+	results, err := m.loadScanResults(ctx, scanResults)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := m.planRemediation(results)
+	fileName := remediationPlanFileName
+
+	if apply {
+		if err := m.applyRemediation(ctx, &plan); err != nil {
+			return nil, err
+		}
+
+		fileName = remediationResultsFileName
+	}
+
+	planAsJSON, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return nil, WrapError(err, "failed to marshal the remediation plan")
+	}
+
+	container := m.Ctr
+	if container == nil {
+		return nil, Errorf("failed to get container for the remediation plan")
+	}
+
+	return container.Directory("mnt/").
+		WithNewFile(fileName, string(planAsJSON)).
+		File(fileName), nil
+}
+
+// planRemediation computes the tag changes of every non-compliant resource
+func (m *AwsTagInspector) planRemediation(results []ScanResult) remediationPlan {
+	plan := remediationPlan{
+		DryRun:    true,
+		Resources: make([]resourceRemediation, 0),
+	}
+
+	for _, result := range sortedResults(results) {
+		if result.ComplianceTag != "non-compliant" {
+			continue
+		}
+
+		remediation := m.planResourceRemediation(result)
+		if len(remediation.Changes) == 0 && len(remediation.Unresolved) == 0 {
+			continue
+		}
+
+		plan.Resources = append(plan.Resources, remediation)
+		plan.Summary.Changes += len(remediation.Changes)
+
+		if len(remediation.Unresolved) > 0 {
+			plan.Summary.Unresolved++
+		}
+	}
+
+	plan.Summary.Resources = len(plan.Resources)
+
+	return plan
+}
+
+// planResourceRemediation computes the tag changes of a resource, from the criteria of its resource
+// type. The specific tags of the criteria take precedence over the ones of the compliance level,
+// and both over the default values.
+func (m *AwsTagInspector) planResourceRemediation(result ScanResult) resourceRemediation {
+	remediation := resourceRemediation{
+		ResourceType: result.ResourceType,
+		ResourceID:   result.ResourceID,
+		ARN:          result.ARN,
+		Region:       result.Region,
+		AccountID:    result.AccountID,
+		Changes:      make([]tagChange, 0),
+		Status:       remediationStatusPlanned,
+	}
+
+	criteria := m.resourceTagCriteria(m.resourceConfigKey(result.ResourceType))
+
+	// The compliance level can be overridden per resource, as in the scan
+	if level, exists := result.Tags["ComplianceLevel"]; exists {
+		if _, known := m.Cfg.ComplianceLevels[level]; known {
+			criteria.ComplianceLevel = level
+		}
+	}
+
+	level := m.Cfg.ComplianceLevels[criteria.ComplianceLevel]
+	planned := map[string]bool{}
+
+	setTag := func(key, value, source string) {
+		if key == "" || planned[key] {
+			return
+		}
+
+		planned[key] = true
+
+		current, exists := result.Tags[key]
+
+		switch {
+		case !exists:
+			remediation.Changes = append(remediation.Changes, tagChange{
+				Key: key, Action: tagChangeAdd, Value: value, Source: source,
+			})
+		case current != value:
+			remediation.Changes = append(remediation.Changes, tagChange{
+				Key: key, Action: tagChangeUpdate, Value: value, CurrentValue: current, Source: source,
+			})
+		}
+	}
+
+	for _, key := range sortedTagKeys(criteria.SpecificTags) {
+		setTag(key, criteria.SpecificTags[key], "specific_tags")
+	}
+
+	for _, key := range sortedTagKeys(level.SpecificTags) {
+		setTag(key, level.SpecificTags[key], fmt.Sprintf("compliance_levels.%s.specific_tags", criteria.ComplianceLevel))
+	}
+
+	for _, key := range append(append([]string{}, criteria.RequiredTags...), level.RequiredTags...) {
+		if _, exists := result.Tags[key]; exists || key == "" || planned[key] {
+			continue
+		}
+
+		if value, hasDefault := m.Cfg.Remediation.DefaultValues[key]; hasDefault {
+			setTag(key, value, defaultValuesSource)
+
+			continue
+		}
+
+		planned[key] = true
+		remediation.Unresolved = append(remediation.Unresolved, key)
+	}
+
+	if len(remediation.Changes) == 0 {
+		remediation.Status = remediationStatusUnresolved
+	}
+
+	return remediation
+}
+
+// resourceConfigKey returns the key of the resources section of the configuration that a resource
+// type was scanned with, e.g. "ec2" for "ec2:instance". If there's none, the global criteria apply.
+func (m *AwsTagInspector) resourceConfigKey(resourceType string) string {
+	if _, exists := m.Cfg.Resources[resourceType]; exists {
+		return resourceType
+	}
+
+	service, _, _ := strings.Cut(resourceType, ":")
+	if _, exists := m.Cfg.Resources[service]; exists {
+		return service
+	}
+
+	return ""
+}
+
+// remediationTarget is an account and region whose resources are tagged with the same client
+type remediationTarget struct {
+	accountID string
+	region    string
+}
+
+// applyRemediation applies the planned changes, and records the outcome of each resource in the plan.
+// The resources of each account and region are tagged concurrently.
+func (m *AwsTagInspector) applyRemediation(ctx context.Context, plan *remediationPlan) error {
+	awsClient, err := m.setupAWSCredentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	plan.DryRun = false
+
+	var targets []remediationTarget
+
+	resourcesByTarget := map[remediationTarget][]int{}
+
+	for idx, remediation := range plan.Resources {
+		if len(remediation.Changes) == 0 {
+			continue
+		}
+
+		target := remediationTarget{accountID: remediation.AccountID, region: remediation.Region}
+		if _, exists := resourcesByTarget[target]; !exists {
+			targets = append(targets, target)
+		}
+
+		resourcesByTarget[target] = append(resourcesByTarget[target], idx)
+	}
+
+	for _, target := range targets {
+		indexes := resourcesByTarget[target]
+
+		targetClient, err := m.remediationClient(ctx, awsClient, target)
+		if err != nil {
+			for _, idx := range indexes {
+				plan.Resources[idx].Status = remediationStatusFailed
+				plan.Resources[idx].Error = err.Error()
+			}
+
+			continue
+		}
+
+		var appliers errgroup.Group
+		appliers.SetLimit(maxConcurrentRemediations)
+
+		// Each resource writes to its own index, so no lock is needed.
+		for _, idx := range indexes {
+			appliers.Go(func() error {
+				remediation := &plan.Resources[idx]
+
+				if err := applyTagChanges(ctx, targetClient, *remediation); err != nil {
+					remediation.Status = remediationStatusFailed
+					remediation.Error = err.Error()
+
+					return nil
+				}
+
+				remediation.Status = remediationStatusApplied
+
+				return nil
+			})
+		}
+
+		_ = appliers.Wait()
+	}
+
+	for _, remediation := range plan.Resources {
+		switch remediation.Status {
+		case remediationStatusApplied:
+			plan.Summary.Applied++
+		case remediationStatusFailed:
+			plan.Summary.Failed++
+		}
+	}
+
+	return nil
+}
+
+// remediationClient returns the client of an account (with the role of the configuration assumed,
+// if it's a member account) and region, with the credentials of the given client
+func (m *AwsTagInspector) remediationClient(
+	ctx context.Context,
+	awsClient *AWSClient,
+	target remediationTarget,
+) (*AWSClient, error) {
+	if target.accountID != "" {
+		accountClient, err := m.accountClient(ctx, awsClient, target.accountID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to access account %s: %w", target.accountID, err)
+		}
+
+		awsClient = accountClient
+	}
+
+	if target.region == "" || target.region == awsClient.Region() {
+		return awsClient, nil
+	}
+
+	return awsClient.ForRegion(target.region)
+}
+
+// applyTagChanges sets the planned tags of a resource with the tagging API of its service
+func applyTagChanges(ctx context.Context, awsClient *AWSClient, remediation resourceRemediation) error {
+	tags := make(map[string]string, len(remediation.Changes))
+	for _, change := range remediation.Changes {
+		tags[change.Key] = change.Value
+	}
+
+	switch remediation.ResourceType {
+	case "ec2:instance":
+		return tagEC2Instance(ctx, awsClient, remediation.ResourceID, tags)
+	case "s3:bucket":
+		return tagS3Bucket(ctx, awsClient, remediation.ResourceID, tags)
+	default:
+		return tagWithTaggingAPI(ctx, awsClient, remediation.ARN, tags)
+	}
+}
+
+// tagEC2Instance sets tags on an instance with CreateTags, which adds or overwrites them
+func tagEC2Instance(ctx context.Context, awsClient *AWSClient, instanceID string, tags map[string]string) error {
+	client, err := awsClient.GetEC2Client()
+	if err != nil {
+		return fmt.Errorf("failed to initialize EC2 client: %w", err)
+	}
+
+	ec2Tags := make([]ec2types.Tag, 0, len(tags))
+	for _, key := range sortedTagKeys(tags) {
+		ec2Tags = append(ec2Tags, ec2types.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+
+	if _, err := client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{instanceID},
+		Tags:      ec2Tags,
+	}); err != nil {
+		return fmt.Errorf("failed to tag instance %s: %w", instanceID, err)
+	}
+
+	return nil
+}
+
+// tagS3Bucket sets tags on a bucket. PutBucketTagging replaces the whole tag set, so the tags are
+// merged with the current ones of the bucket, not the ones of the scan, which could be outdated.
+func tagS3Bucket(ctx context.Context, awsClient *AWSClient, bucketName string, tags map[string]string) error {
+	client, err := awsClient.GetS3Client()
+	if err != nil {
+		return fmt.Errorf("failed to initialize S3 client: %w", err)
+	}
+
+	merged := make(map[string]string, len(tags))
+
+	current, err := client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "NoSuchTagSet" {
+			return fmt.Errorf("failed to get the tags of bucket %s: %w", bucketName, err)
+		}
+	} else {
+		for _, tag := range current.TagSet {
+			merged[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+
+	for key, value := range tags {
+		merged[key] = value
+	}
+
+	tagSet := make([]s3types.Tag, 0, len(merged))
+	for _, key := range sortedTagKeys(merged) {
+		tagSet = append(tagSet, s3types.Tag{Key: aws.String(key), Value: aws.String(merged[key])})
+	}
+
+	if _, err := client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(bucketName),
+		Tagging: &s3types.Tagging{TagSet: tagSet},
+	}); err != nil {
+		return fmt.Errorf("failed to tag bucket %s: %w", bucketName, err)
+	}
+
+	return nil
+}
+
+// tagWithTaggingAPI sets tags on any resource supported by the Resource Groups Tagging API, by its ARN
+func tagWithTaggingAPI(ctx context.Context, awsClient *AWSClient, resourceARN string, tags map[string]string) error {
+	if resourceARN == "" {
+		return fmt.Errorf("the resource has no ARN, it can't be tagged with the tagging API")
+	}
+
+	client, err := awsClient.GetTaggingClient()
+	if err != nil {
+		return fmt.Errorf("failed to initialize tagging API client: %w", err)
+	}
+
+	output, err := client.TagResources(ctx, &resourcegroupstaggingapi.TagResourcesInput{
+		ResourceARNList: []string{resourceARN},
+		Tags:            tags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to tag resource %s: %w", resourceARN, err)
+	}
+
+	if failure, failed := output.FailedResourcesMap[resourceARN]; failed {
+		return fmt.Errorf("failed to tag resource %s: %s", resourceARN, aws.ToString(failure.ErrorMessage))
+	}
+
+	return nil
+}
+
+// sortedTagKeys returns the keys of the tags, sorted
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
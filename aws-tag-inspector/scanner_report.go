@@ -0,0 +1,596 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	reportFormatJSON     = "json"
+	reportFormatCSV      = "csv"
+	reportFormatMarkdown = "markdown"
+	reportFormatHTML     = "html"
+	reportFormatSARIF    = "sarif"
+	// reportFileBaseName is the name of the report file, without its extension.
+	reportFileBaseName = "scan-results"
+	sarifSchema        = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion       = "2.1.0"
+	sarifToolName      = "aws-tag-inspector"
+	sarifToolURI       = "https://github.com/Excoriate/daggerverse/tree/main/aws-tag-inspector"
+	// sarifResourceURIPrefix is the first segment of the synthetic URIs of the resources in the SARIF report.
+	sarifResourceURIPrefix = "aws"
+	// defaultIssueRuleID is the rule ID of the issues that don't match any known rule.
+	defaultIssueRuleID = "tag-compliance"
+)
+
+// reportFileExtensions maps the report formats to the extensions of their files.
+//
+//nolint:gochecknoglobals // It's a read-only lookup table.
+var reportFileExtensions = map[string]string{
+	reportFormatJSON:     "json",
+	reportFormatCSV:      "csv",
+	reportFormatMarkdown: "md",
+	reportFormatHTML:     "html",
+	reportFormatSARIF:    "sarif",
+}
+
+// issueRuleRegex matches the rule cited by an issue, e.g. "(rule: rules.prod-data-protection)".
+var issueRuleRegex = regexp.MustCompile(`\(rule: ([^)]+)\)$`)
+
+// issueRulePrefixes maps the prefixes of the issues of the tag criteria to their rule IDs.
+//
+//nolint:gochecknoglobals // It's a read-only lookup table.
+var issueRulePrefixes = []struct {
+	prefix string
+	ruleID string
+}{
+	{"Resource has no tags", "untagged-resource"},
+	{"Insufficient tags", "minimum-required-tags"},
+	{"Missing required tag (compliance level)", "compliance-level"},
+	{"Tag mismatch (compliance level)", "compliance-level"},
+	{"Unknown compliance level", "compliance-level"},
+	{"Missing required tag", "required-tags"},
+	{"Contains forbidden tag", "forbidden-tags"},
+	{"Tag mismatch", "specific-tags"},
+}
+
+// renderReport renders the scan results in the given format, and returns the report and its file name.
+// Every format is rendered from the same results: "json" (default), "csv", "markdown", "html" or "sarif".
+func (m *AwsTagInspector) renderReport(format string, results []ScanResult) (string, string, error) {
+	if format == "" {
+		format = reportFormatJSON
+	}
+
+	format = strings.ToLower(format)
+	if format == "md" {
+		format = reportFormatMarkdown
+	}
+
+	extension, supported := reportFileExtensions[format]
+	if !supported {
+		return "", "", fmt.Errorf("unsupported report format %s, expected json, csv, markdown, html or sarif", format)
+	}
+
+	var (
+		report string
+		err    error
+	)
+
+	switch format {
+	case reportFormatCSV:
+		report, err = renderCSVReport(results)
+	case reportFormatMarkdown:
+		report = renderMarkdownReport(results)
+	case reportFormatHTML:
+		report, err = renderHTMLReport(results)
+	case reportFormatSARIF:
+		report, err = renderSARIFReport(results)
+	default:
+		report, err = m.formatResults(results)
+	}
+
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render the %s report: %w", format, err)
+	}
+
+	return report, reportFileBaseName + "." + extension, nil
+}
+
+// renderCSVReport renders one row per resource, for spreadsheets. The issues and the tags are
+// joined in a single cell each, and the cells are escaped so spreadsheets don't run them as formulas.
+func renderCSVReport(results []ScanResult) (string, error) {
+	var buf bytes.Buffer
+
+	writer := csv.NewWriter(&buf)
+
+	rows := [][]string{{
+		"account_id", "region", "resource_type", "resource_id", "arn",
+		"compliance", "issues", "exclusion_reason", "tags",
+	}}
+
+	for _, result := range sortedResults(results) {
+		row := []string{
+			result.AccountID,
+			result.Region,
+			result.ResourceType,
+			result.ResourceID,
+			result.ARN,
+			result.ComplianceTag,
+			strings.Join(result.Issues, "; "),
+			result.ExclusionReason,
+			formatTags(result.Tags),
+		}
+
+		for i, cell := range row {
+			row[i] = escapeCSVCell(cell)
+		}
+
+		rows = append(rows, row)
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// renderMarkdownReport renders the summary and the non-compliant and excluded resources, for PR comments.
+func renderMarkdownReport(results []ScanResult) string {
+	summary := summarizeResults(results)
+
+	var md strings.Builder
+
+	md.WriteString("# AWS Tag Compliance Report\n\n")
+	md.WriteString("| Total resources | Compliant | Non-compliant | Excluded |\n")
+	md.WriteString("| --- | --- | --- | --- |\n")
+	fmt.Fprintf(&md, "| %d | %d | %d | %d |\n", summary.TotalResources,
+		summary.Compliance.Compliant, summary.Compliance.NonCompliant, len(summary.Excluded))
+
+	writeMarkdownCounts(&md, "Account", summary.Accounts)
+	writeMarkdownCounts(&md, "Region", summary.Regions)
+
+	var nonCompliant []ScanResult
+
+	for _, result := range sortedResults(results) {
+		if result.ComplianceTag != "compliant" && result.ComplianceTag != complianceTagExcluded {
+			nonCompliant = append(nonCompliant, result)
+		}
+	}
+
+	md.WriteString("\n## Non-compliant resources\n\n")
+
+	if len(nonCompliant) == 0 {
+		md.WriteString("All the scanned resources are compliant.\n")
+	} else {
+		md.WriteString("| Resource | Type | Region | Issues |\n")
+		md.WriteString("| --- | --- | --- | --- |\n")
+
+		for _, result := range nonCompliant {
+			issues := make([]string, 0, len(result.Issues))
+			for _, issue := range result.Issues {
+				issues = append(issues, escapeMarkdownCell(issue))
+			}
+
+			fmt.Fprintf(&md, "| `%s` | %s | %s | %s |\n", escapeMarkdownCell(result.ResourceID),
+				result.ResourceType, result.Region, strings.Join(issues, "<br>"))
+		}
+	}
+
+	if len(summary.Excluded) > 0 {
+		md.WriteString("\n## Excluded resources\n\n")
+		md.WriteString("| Resource | Type | Reason |\n")
+		md.WriteString("| --- | --- | --- |\n")
+
+		for _, result := range sortedResults(summary.Excluded) {
+			fmt.Fprintf(&md, "| `%s` | %s | %s |\n", escapeMarkdownCell(result.ResourceID),
+				result.ResourceType, escapeMarkdownCell(result.ExclusionReason))
+		}
+	}
+
+	return md.String()
+}
+
+// writeMarkdownCounts writes a table with the compliance counts of each account or region, if any
+func writeMarkdownCounts(md *strings.Builder, title string, counts map[string]complianceSummary) {
+	if len(counts) == 0 {
+		return
+	}
+
+	fmt.Fprintf(md, "\n| %s | Total | Compliant | Non-compliant |\n", title)
+	md.WriteString("| --- | --- | --- | --- |\n")
+
+	for _, key := range sortedKeys(counts) {
+		fmt.Fprintf(md, "| %s | %d | %d | %d |\n", key,
+			counts[key].TotalResources, counts[key].Compliant, counts[key].NonCompliant)
+	}
+}
+
+// htmlReportData is the data of the HTML report template.
+type htmlReportData struct {
+	Summary       scanSummary
+	Results       []ScanResult
+	ResourceTypes []string
+	Regions       []string
+	Accounts      []string
+}
+
+// renderHTMLReport renders a standalone HTML page with every resource, which can be filtered by
+// compliance, resource type, region, account and text, without any external asset.
+func renderHTMLReport(results []ScanResult) (string, error) {
+	summary := summarizeResults(results)
+	sorted := sortedResults(results)
+
+	data := htmlReportData{
+		Summary: summary,
+		Results: sorted,
+	}
+
+	seen := map[string]bool{}
+
+	for _, result := range sorted {
+		for _, value := range []struct {
+			key    string
+			target *[]string
+		}{
+			{"type:" + result.ResourceType, &data.ResourceTypes},
+			{"region:" + result.Region, &data.Regions},
+			{"account:" + result.AccountID, &data.Accounts},
+		} {
+			_, name, _ := strings.Cut(value.key, ":")
+			if name != "" && !seen[value.key] {
+				seen[value.key] = true
+				*value.target = append(*value.target, name)
+			}
+		}
+	}
+
+	sort.Strings(data.ResourceTypes)
+	sort.Strings(data.Regions)
+	sort.Strings(data.Accounts)
+
+	var buf bytes.Buffer
+	if err := htmlReportTemplate.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// sarifReport is a SARIF 2.1.0 log, with a single run.
+type sarifReport struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// renderSARIFReport renders an error per issue of the non-compliant resources, for code-scanning
+// dashboards. Resources aren't files, so they're referenced by logical locations (their ARN), and
+// by a synthetic physical location, which code scanning requires on every result.
+func renderSARIFReport(results []ScanResult) (string, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           sarifToolName,
+			InformationURI: sarifToolURI,
+			Rules:          make([]sarifRule, 0),
+		}},
+		Results: make([]sarifResult, 0),
+	}
+
+	rules := map[string]bool{}
+
+	for _, result := range sortedResults(results) {
+		for _, issue := range result.Issues {
+			ruleID := issueRuleID(issue)
+			if !rules[ruleID] {
+				rules[ruleID] = true
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+					ID:               ruleID,
+					ShortDescription: sarifMessage{Text: "Tag compliance rule " + ruleID},
+				})
+			}
+
+			location := result.ARN
+			if location == "" {
+				location = result.ResourceID
+			}
+
+			run.Results = append(run.Results, sarifResult{
+				RuleID:  ruleID,
+				Level:   "error",
+				Message: sarifMessage{Text: fmt.Sprintf("%s %s: %s", result.ResourceType, result.ResourceID, issue)},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: sarifResourceURI(result)},
+						Region:           sarifRegion{StartLine: 1},
+					},
+					LogicalLocations: []sarifLogicalLocation{{
+						Name:               result.ResourceID,
+						FullyQualifiedName: location,
+						Kind:               "resource",
+					}},
+				}},
+				Properties: map[string]string{
+					"resourceType": result.ResourceType,
+					"region":       result.Region,
+					"accountId":    result.AccountID,
+				},
+			})
+		}
+	}
+
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	report, err := json.MarshalIndent(sarifReport{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(report), nil
+}
+
+// sarifResourceURI returns the synthetic relative URI of a resource, made of its account, region,
+// type and ID, e.g. "aws/123456789012/us-east-1/ec2:instance/i-0abc", with each segment escaped
+func sarifResourceURI(result ScanResult) string {
+	segments := []string{sarifResourceURIPrefix}
+
+	for _, segment := range []string{result.AccountID, result.Region, result.ResourceType, result.ResourceID} {
+		if segment == "" {
+			segment = "unknown"
+		}
+
+		segments = append(segments, url.PathEscape(segment))
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// issueRuleID returns the ID of the rule an issue breaks: the rule it cites, or the tag criteria it's about
+func issueRuleID(issue string) string {
+	if match := issueRuleRegex.FindStringSubmatch(issue); match != nil {
+		return match[1]
+	}
+
+	for _, candidate := range issueRulePrefixes {
+		if strings.HasPrefix(issue, candidate.prefix) {
+			return candidate.ruleID
+		}
+	}
+
+	return defaultIssueRuleID
+}
+
+// sortedResults returns a copy of the results sorted by account, region, resource type and ID,
+// so the reports are stable across scans
+func sortedResults(results []ScanResult) []ScanResult {
+	sorted := append([]ScanResult(nil), results...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.AccountID != b.AccountID {
+			return a.AccountID < b.AccountID
+		}
+
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+
+		if a.ResourceType != b.ResourceType {
+			return a.ResourceType < b.ResourceType
+		}
+
+		return a.ResourceID < b.ResourceID
+	})
+
+	return sorted
+}
+
+// sortedKeys returns the keys of the compliance counts, sorted
+func sortedKeys(counts map[string]complianceSummary) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// formatTags formats the tags as "key=value" pairs sorted by key, separated by "; "
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "; ")
+}
+
+// escapeCSVCell prefixes the cells starting with a formula character with a quote, so the tags
+// and names set by anyone with tagging permissions can't inject formulas in a spreadsheet
+func escapeCSVCell(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+
+	return text
+}
+
+// escapeMarkdownCell escapes the characters that would break a Markdown table cell
+func escapeMarkdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+
+	return strings.ReplaceAll(text, "\n", " ")
+}
+
+// htmlReportTemplate is the template of the standalone HTML report. The filters are applied
+// client-side, with the data attributes of the rows.
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"tags": formatTags,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>AWS Tag Compliance Report</title>
+<style>
+  body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 2rem; color: #1f2328; }
+  .cards { display: flex; gap: 1rem; margin-bottom: 1.5rem; }
+  .card { border: 1px solid #d0d7de; border-radius: 6px; padding: 0.75rem 1.25rem; }
+  .card strong { display: block; font-size: 1.5rem; }
+  .filters { display: flex; gap: 0.75rem; margin-bottom: 1rem; flex-wrap: wrap; }
+  table { border-collapse: collapse; width: 100%; }
+  th, td { border: 1px solid #d0d7de; padding: 0.4rem 0.6rem; text-align: left; vertical-align: top; }
+  th { background: #f6f8fa; }
+  .compliant { color: #1a7f37; }
+  .non-compliant { color: #cf222e; }
+  .excluded { color: #6e7781; }
+  ul { margin: 0; padding-left: 1.1rem; }
+</style>
+</head>
+<body>
+<h1>AWS Tag Compliance Report</h1>
+<div class="cards">
+  <div class="card"><strong>{{.Summary.TotalResources}}</strong>Resources scanned</div>
+  <div class="card compliant"><strong>{{.Summary.Compliance.Compliant}}</strong>Compliant</div>
+  <div class="card non-compliant"><strong>{{.Summary.Compliance.NonCompliant}}</strong>Non-compliant</div>
+  <div class="card excluded"><strong>{{len .Summary.Excluded}}</strong>Excluded</div>
+</div>
+<div class="filters">
+  <select id="filter-compliance" aria-label="Compliance">
+    <option value="">All statuses</option>
+    <option value="compliant">Compliant</option>
+    <option value="non-compliant">Non-compliant</option>
+    <option value="excluded">Excluded</option>
+  </select>
+  <select id="filter-type" aria-label="Resource type">
+    <option value="">All resource types</option>
+    {{range .ResourceTypes}}<option value="{{.}}">{{.}}</option>{{end}}
+  </select>
+  <select id="filter-region" aria-label="Region">
+    <option value="">All regions</option>
+    {{range .Regions}}<option value="{{.}}">{{.}}</option>{{end}}
+  </select>
+  {{if .Accounts}}<select id="filter-account" aria-label="Account">
+    <option value="">All accounts</option>
+    {{range .Accounts}}<option value="{{.}}">{{.}}</option>{{end}}
+  </select>{{end}}
+  <input id="filter-text" type="search" placeholder="Search resources, tags or issues" aria-label="Search">
+</div>
+<table>
+  <thead>
+    <tr><th>Resource</th><th>Type</th><th>Region</th><th>Account</th><th>Status</th><th>Issues</th><th>Tags</th></tr>
+  </thead>
+  <tbody>
+  {{range .Results}}
+    <tr data-compliance="{{.ComplianceTag}}" data-type="{{.ResourceType}}" data-region="{{.Region}}" data-account="{{.AccountID}}">
+      <td title="{{.ARN}}">{{.ResourceID}}</td>
+      <td>{{.ResourceType}}</td>
+      <td>{{.Region}}</td>
+      <td>{{.AccountID}}</td>
+      <td class="{{.ComplianceTag}}">{{.ComplianceTag}}</td>
+      <td>{{if .ExclusionReason}}{{.ExclusionReason}}{{else}}<ul>{{range .Issues}}<li>{{.}}</li>{{end}}</ul>{{end}}</td>
+      <td>{{tags .Tags}}</td>
+    </tr>
+  {{end}}
+  </tbody>
+</table>
+<script>
+  (function () {
+    var filters = {
+      compliance: document.getElementById("filter-compliance"),
+      type: document.getElementById("filter-type"),
+      region: document.getElementById("filter-region"),
+      account: document.getElementById("filter-account")
+    };
+    var text = document.getElementById("filter-text");
+    function apply() {
+      var query = text.value.toLowerCase();
+      document.querySelectorAll("tbody tr").forEach(function (row) {
+        var visible = Object.keys(filters).every(function (key) {
+          var filter = filters[key];
+          return !filter || !filter.value || row.dataset[key] === filter.value;
+        });
+        row.hidden = !(visible && row.textContent.toLowerCase().indexOf(query) !== -1);
+      });
+    }
+    Object.keys(filters).forEach(function (key) {
+      if (filters[key]) { filters[key].addEventListener("change", apply); }
+    });
+    text.addEventListener("input", apply);
+  })();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultOneOfSeparator separates the values held by the tag referenced in values_from_tag.
+	defaultOneOfSeparator = ","
+	// awsReservedTagPrefix is the prefix of the tags managed by AWS, which don't follow the key casing.
+	awsReservedTagPrefix = "aws:"
+)
+
+// ruleIDRegex matches the IDs of the rules, e.g. "prod-data-protection".
+var ruleIDRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// keyCasePatterns are the key casings a rule can enforce.
+//
+//nolint:gochecknoglobals // It's a read-only lookup table.
+var keyCasePatterns = map[string]*regexp.Regexp{
+	"PascalCase": regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`),
+	"camelCase":  regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`),
+	"snake_case": regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`),
+	"kebab-case": regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`),
+	"lowercase":  regexp.MustCompile(`^[^A-Z]+$`),
+	"UPPERCASE":  regexp.MustCompile(`^[^a-z]+$`),
+}
+
+// ruleValues is a list of values, which can be written in the YAML config as a single value or a list.
+type ruleValues []string
+
+// UnmarshalYAML implements yaml.Unmarshaler, accepting a scalar or a sequence
+func (v *ruleValues) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*v = ruleValues{value.Value}
+
+		return nil
+	}
+
+	var values []string
+	if err := value.Decode(&values); err != nil {
+		return err
+	}
+
+	*v = values
+
+	return nil
+}
+
+// appliesTo checks if the rule applies to the resource: its type is one of the rule's resource types
+// (or the rule has none), and its tags meet the condition of the rule.
+func (r tagRule) appliesTo(resource BaseResource) bool {
+	if len(r.ResourceTypes) > 0 && !matchesResourceType(r.ResourceTypes, resource.ResourceType) {
+		return false
+	}
+
+	for key, values := range r.When.Tags {
+		value, exists := resource.GetTagValue(key)
+		if !exists || !containsValue(values, value) {
+			return false
+		}
+	}
+
+	for _, key := range r.When.TagsPresent {
+		if !resource.HasTag(key) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// evaluate returns the issues of the resource's tags against the requirements of the rule.
+// It doesn't check whether the rule applies to the resource.
+func (r tagRule) evaluate(resource BaseResource) []string {
+	var issues []string
+
+	for _, key := range r.Require.Tags {
+		if !resource.HasTag(key) {
+			issues = append(issues, r.issue("missing required tag %s", key))
+		}
+	}
+
+	if oneOf := r.Require.OneOf; oneOf != nil {
+		if value, exists := resource.GetTagValue(oneOf.Tag); exists {
+			allowed := oneOf.allowedValues(resource)
+			if !containsValue(allowed, value) {
+				issues = append(issues, r.issue("%s=%s is not one of [%s]", oneOf.Tag, value, strings.Join(allowed, ", ")))
+			}
+		}
+	}
+
+	if pattern, exists := keyCasePatterns[r.Require.KeyCase]; exists {
+		keys := make([]string, 0, len(resource.Tags))
+		for key := range resource.Tags {
+			keys = append(keys, key)
+		}
+
+		// Sorted, so the issues are reported in a stable order
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if !strings.HasPrefix(key, awsReservedTagPrefix) && !pattern.MatchString(key) {
+				issues = append(issues, r.issue("tag key %s is not %s", key, r.Require.KeyCase))
+			}
+		}
+	}
+
+	return issues
+}
+
+// issue formats an issue of the rule, citing its ID
+func (r tagRule) issue(format string, args ...interface{}) string {
+	return fmt.Sprintf("Rule violation: %s (rule: rules.%s)", fmt.Sprintf(format, args...), r.ID)
+}
+
+// allowedValues returns the values the tag can have: the static values, or the values held by
+// the tag referenced in values_from_tag. If that tag is missing, no value is allowed.
+func (o *oneOfRequirement) allowedValues(resource BaseResource) []string {
+	if o.ValuesFromTag == "" {
+		return o.Values
+	}
+
+	separator := o.Separator
+	if separator == "" {
+		separator = defaultOneOfSeparator
+	}
+
+	source, _ := resource.GetTagValue(o.ValuesFromTag)
+
+	var values []string
+
+	for _, value := range strings.Split(source, separator) {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			values = append(values, trimmed)
+		}
+	}
+
+	return values
+}
+
+// evaluateTagRules evaluates the conditional tagging rules that apply to the resource, and returns their issues
+func evaluateTagRules(resource BaseResource, rules []tagRule) []string {
+	var issues []string
+
+	for _, rule := range rules {
+		if rule.appliesTo(resource) {
+			issues = append(issues, rule.evaluate(resource)...)
+		}
+	}
+
+	return issues
+}
+
+// matchesResourceType checks if the resource type (e.g. "ec2:instance") is one of the given types,
+// either by its full name or by its service (e.g. "ec2")
+func matchesResourceType(resourceTypes []string, resourceType string) bool {
+	service, _, _ := strings.Cut(resourceType, ":")
+
+	for _, candidate := range resourceTypes {
+		if candidate == resourceType || candidate == service {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsValue checks if the value is one of the values
+func containsValue(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,6 +2,10 @@ package main
 
 import (
 	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 )
 
 // ScanResult represents the result of scanning a resource
@@ -18,6 +22,9 @@ type ScanResult struct {
 	// Region is the AWS region where the resource is located
 	Region string `json:"region"`
 
+	// AccountID is the AWS account of the resource, set when scanning several accounts
+	AccountID string `json:"account_id,omitempty"`
+
 	// Tags is a map of key-value pairs representing the resource's tags
 	Tags map[string]string `json:"tags"`
 
@@ -29,6 +36,9 @@ type ScanResult struct {
 
 	// ComplianceTag indicates the overall compliance status
 	ComplianceTag string `json:"compliance_tag,omitempty"`
+
+	// ExclusionReason is the reason why the resource was skipped, set if it's excluded from the scan
+	ExclusionReason string `json:"exclusion_reason,omitempty"`
 }
 
 type Scanner interface {
@@ -195,6 +205,59 @@ func (r *BaseResource) ScanTags(criteria TagCriteria, complianceLevels map[strin
 	return issues
 }
 
+// ValidateTagValues validates the values of the resource's tags against the tag_validation rules:
+// the allowed values of a tag, and the pattern its value must match. Tags without a rule, and
+// missing tags, aren't checked here. Each issue cites the rule that isn't met.
+func (r *BaseResource) ValidateTagValues(validation tagValidation) []string {
+	keys := make([]string, 0, len(r.Tags))
+	for key := range r.Tags {
+		keys = append(keys, key)
+	}
+
+	// Sorted, so the issues are reported in a stable order
+	sort.Strings(keys)
+
+	var issues []string
+
+	for _, key := range keys {
+		value := r.Tags[key]
+
+		if allowed, exists := validation.AllowedValues[key]; exists && !slices.Contains(allowed, value) {
+			issues = append(issues, fmt.Sprintf(
+				"Invalid tag value: %s=%s is not one of [%s] (rule: tag_validation.allowed_values.%s)",
+				key, value, strings.Join(allowed, ", "), key))
+		}
+
+		if pattern, exists := validation.patternRule(key); exists && !pattern.MatchString(value) {
+			issues = append(issues, fmt.Sprintf(
+				"Malformed tag value: %s=%s does not match %s (rule: tag_validation.pattern_rules.%s)",
+				key, value, pattern.String(), key))
+		}
+	}
+
+	return issues
+}
+
+// patternRule returns the regular expression of the pattern rule of a tag, if it has one. The patterns
+// compiled when the configuration is loaded aren't kept between calls, so it's compiled if needed.
+func (v tagValidation) patternRule(key string) (*regexp.Regexp, bool) {
+	if compiled, exists := v.compiledRules[key]; exists {
+		return compiled, true
+	}
+
+	pattern, exists := v.PatternRules[key]
+	if !exists {
+		return nil, false
+	}
+
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, false
+	}
+
+	return compiled, true
+}
+
 // ValidateCompliance checks if the resource is compliant with tag criteria
 func (r *BaseResource) ValidateCompliance(criteria TagCriteria, complianceLevels map[string]complianceLevel) bool {
 	return len(r.ScanTags(criteria, complianceLevels)) == 0
@@ -213,3 +276,46 @@ func (r *BaseResource) GetMetadata() map[string]interface{} {
 func (r *BaseResource) IsExcluded(configLoader *configLoader) (bool, string) {
 	return configLoader.isResourceExcluded(r.ResourceType, r.ResourceID)
 }
+
+// newScanResult scans the tags of a resource against the criteria and the tag validation rules,
+// and returns its scan result.
+// The compliance level can be overridden per resource with the ComplianceLevel tag, if the level
+// is defined in the configuration.
+func newScanResult(resource BaseResource, criteria TagCriteria, config *inspectorConfig) ScanResult {
+	var complianceLevels map[string]complianceLevel
+	if config != nil {
+		complianceLevels = config.ComplianceLevels
+	}
+
+	if level, exists := resource.GetTagValue("ComplianceLevel"); exists {
+		if _, known := complianceLevels[level]; known {
+			criteria.ComplianceLevel = level
+		}
+	}
+
+	result := ScanResult{
+		ResourceType: resource.ResourceType,
+		ResourceID:   resource.ResourceID,
+		ARN:          resource.ARN,
+		Region:       resource.Region,
+		Tags:         resource.GetTags(),
+		Metadata:     resource.Metadata,
+		Issues:       resource.ScanTags(criteria, complianceLevels),
+	}
+
+	// Validate the tag values against the allowed values and the pattern rules, and evaluate
+	// the conditional tagging rules
+	if config != nil {
+		result.Issues = append(result.Issues, resource.ValidateTagValues(config.TagValidation)...)
+		result.Issues = append(result.Issues, evaluateTagRules(resource, config.Rules)...)
+	}
+
+	// Set compliance tag based on issues
+	if len(result.Issues) == 0 {
+		result.ComplianceTag = "compliant"
+	} else {
+		result.ComplianceTag = "non-compliant"
+	}
+
+	return result
+}
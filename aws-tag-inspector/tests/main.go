@@ -72,6 +72,19 @@ func (m *Tests) TestAll(ctx context.Context) error {
 	polTests.Go(m.TestCloneGitRepo)
 
 	// From this point onwards, we're testing the specific functionality of the AwsTagInspector module.
+	polTests.Go(m.TestScanEC2InstancesWithLocalStandIn)
+	polTests.Go(m.TestScanWithTaggingAPIWithLocalStandIn)
+	polTests.Go(m.TestScanMultipleRegionsWithLocalStandIn)
+	polTests.Go(m.TestScanMultipleAccountsWithLocalStandIn)
+	polTests.Go(m.TestScanWithWebIdentityWithLocalStandIn)
+	polTests.Go(m.TestScanWithSharedCredentialsProfileWithLocalStandIn)
+	polTests.Go(m.TestScanTagValuesWithLocalStandIn)
+	polTests.Go(m.TestScanS3ExclusionsWithLocalStandIn)
+	polTests.Go(m.TestScanS3BucketTagsWithLocalStandIn)
+	polTests.Go(m.TestScanTagRulesWithLocalStandIn)
+	polTests.Go(m.TestScanReportFormatsWithLocalStandIn)
+	polTests.Go(m.TestRemediateWithLocalStandIn)
+	polTests.Go(m.TestNotifyWithLocalStandIns)
 
 	if err := polTests.Wait(); err != nil {
 		return WrapError(err, "there are some failed tests")
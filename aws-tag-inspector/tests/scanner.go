@@ -0,0 +1,1289 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Excoriate/daggerverse/aws-tag-inspector/tests/internal/dagger"
+)
+
+const (
+	// awsStandInImage is the image of the local AWS stand-in used to test the scanners.
+	awsStandInImage = "motoserver/moto:5.0.18"
+	awsStandInPort  = 5000
+	awsStandInHost  = "aws"
+	awsStandInURL   = "http://aws:5000"
+	awsCliImage     = "amazon/aws-cli:2.18.0"
+	awsTestRegion   = "us-east-1"
+	// awsTestSecondRegion is the second region of the multi-region scans.
+	awsTestSecondRegion = "us-west-2"
+	// awsTestAMI is one of the AMIs known by the local AWS stand-in.
+	awsTestAMI = "ami-12c6146b"
+	// smtpSinkImage is the image of the local SMTP sink, whose API lists the received emails.
+	smtpSinkImage   = "axllent/mailpit:v1.20.5"
+	smtpSinkPort    = 1025
+	smtpSinkAPIPort = 8025
+	// webhookStandInImage runs the local HTTP stand-in of the Slack webhooks, which records the posted messages.
+	webhookStandInImage = "python:3.12-alpine"
+	webhookStandInPort  = 8080
+	curlImage           = "curlimages/curl:8.10.1"
+)
+
+// webhookStandInScript is a Slack webhook stand-in: it records the body and the path of each POST,
+// and returns them on GET.
+const webhookStandInScript = `import http.server, json
+requests = []
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_POST(self):
+        body = self.rfile.read(int(self.headers["Content-Length"]))
+        requests.append({"path": self.path, "body": json.loads(body)})
+        self.send_response(200)
+        self.end_headers()
+        self.wfile.write(b"ok")
+    def do_GET(self):
+        body = json.dumps(requests).encode()
+        self.send_response(200)
+        self.send_header("Content-Type", "application/json")
+        self.end_headers()
+        self.wfile.write(body)
+http.server.ThreadingHTTPServer(("", 8080), Handler).serve_forever()
+`
+
+// newAWSStandIn starts a local AWS stand-in, and returns the service and the endpoint to reach it.
+func (m *Tests) newAWSStandIn(ctx context.Context) (*dagger.Service, string, error) {
+	svc, svcErr := dag.
+		Container().
+		From(awsStandInImage).
+		WithExposedPort(awsStandInPort).
+		AsService().
+		Start(ctx)
+
+	if svcErr != nil {
+		return nil, "", WrapError(svcErr, "failed to start the local AWS stand-in")
+	}
+
+	endpoint, endpointErr := svc.Endpoint(ctx, dagger.ServiceEndpointOpts{
+		Scheme: "http",
+	})
+
+	if endpointErr != nil {
+		return nil, "", WrapError(endpointErr, "failed to get the endpoint of the local AWS stand-in")
+	}
+
+	return svc, endpoint, nil
+}
+
+// seedAWSStandIn runs the given AWS CLI commands against the local AWS stand-in, to create the
+// resources to scan.
+func (m *Tests) seedAWSStandIn(ctx context.Context, svc *dagger.Service, commands [][]string) error {
+	ctr := dag.
+		Container().
+		From(awsCliImage).
+		WithServiceBinding(awsStandInHost, svc).
+		WithEnvVariable("AWS_ACCESS_KEY_ID", "test").
+		WithEnvVariable("AWS_SECRET_ACCESS_KEY", "test").
+		WithEnvVariable("AWS_DEFAULT_REGION", awsTestRegion)
+
+	for _, command := range commands {
+		ctr = ctr.
+			WithExec(append([]string{"aws", "--endpoint-url", awsStandInURL}, command...))
+	}
+
+	if _, err := ctr.Sync(ctx); err != nil {
+		return WrapError(err, "failed to seed the local AWS stand-in")
+	}
+
+	return nil
+}
+
+// seedAWSStandInAccount runs the given AWS CLI commands against the local AWS stand-in, in a member
+// account, with the credentials of a role assumed in that account.
+func (m *Tests) seedAWSStandInAccount(
+	ctx context.Context,
+	svc *dagger.Service,
+	accountID, roleName string,
+	commands [][]string,
+) error {
+	// The command is passed as the arguments of the script, and run with the assumed role credentials.
+	assumeRoleAndRun := `args=("$@") && set -- $(aws --endpoint-url ` + awsStandInURL + ` sts assume-role` +
+		` --role-arn arn:aws:iam::` + accountID + `:role/` + roleName + ` --role-session-name seed` +
+		` --query 'Credentials.[AccessKeyId,SecretAccessKey,SessionToken]' --output text)` +
+		` && AWS_ACCESS_KEY_ID=$1 AWS_SECRET_ACCESS_KEY=$2 AWS_SESSION_TOKEN=$3` +
+		` aws --endpoint-url ` + awsStandInURL + ` "${args[@]}"`
+
+	ctr := dag.
+		Container().
+		From(awsCliImage).
+		WithServiceBinding(awsStandInHost, svc).
+		WithEnvVariable("AWS_ACCESS_KEY_ID", "test").
+		WithEnvVariable("AWS_SECRET_ACCESS_KEY", "test").
+		WithEnvVariable("AWS_DEFAULT_REGION", awsTestRegion)
+
+	for _, command := range commands {
+		ctr = ctr.
+			WithExec(append([]string{"bash", "-c", assumeRoleAndRun, "seed"}, command...))
+	}
+
+	if _, err := ctr.Sync(ctx); err != nil {
+		return WrapErrorf(err, "failed to seed the local AWS stand-in in account %s", accountID)
+	}
+
+	return nil
+}
+
+// scanReport is the JSON report of a scan, as written by Scan.
+type scanReport struct {
+	TotalResources int                         `json:"total_resources"`
+	ResourceTypes  map[string][]scanResult     `json:"resource_types"`
+	Compliance     complianceCounts            `json:"compliance"`
+	Regions        map[string]complianceCounts `json:"regions"`
+	Accounts       map[string]complianceCounts `json:"accounts"`
+	Excluded       []scanResult                `json:"excluded"`
+}
+
+// complianceCounts are the compliance counts of a scan report, overall, per region or per account.
+type complianceCounts struct {
+	TotalResources int `json:"total_resources"`
+	Compliant      int `json:"compliant"`
+	NonCompliant   int `json:"non_compliant"`
+}
+
+// scanResult is the result of a resource in a scan report.
+type scanResult struct {
+	ResourceType    string            `json:"resource_type"`
+	ResourceID      string            `json:"resource_id"`
+	ARN             string            `json:"arn"`
+	Region          string            `json:"region"`
+	AccountID       string            `json:"account_id"`
+	Tags            map[string]string `json:"tags"`
+	Issues          []string          `json:"issues"`
+	Metadata        map[string]any    `json:"metadata"`
+	ComplianceTag   string            `json:"compliance_tag"`
+	ExclusionReason string            `json:"exclusion_reason"`
+}
+
+// results returns the scanned (not excluded) results of every resource type of the report.
+func (r scanReport) results() []scanResult {
+	var results []scanResult
+
+	for _, typeResults := range r.ResourceTypes {
+		results = append(results, typeResults...)
+	}
+
+	return results
+}
+
+// resultWithTag returns the scanned result of the resource with the given tag, and an error if
+// there's none.
+func (r scanReport) resultWithTag(key, value string) (scanResult, error) {
+	for _, result := range r.results() {
+		if result.Tags[key] == value {
+			return result, nil
+		}
+	}
+
+	return scanResult{}, Errorf("expected a scanned resource tagged %s=%s, got %+v", key, value, r)
+}
+
+// resultWithID returns the scanned result of the resource with the given ID, and an error if
+// there's none.
+func (r scanReport) resultWithID(resourceID string) (scanResult, error) {
+	for _, result := range r.results() {
+		if result.ResourceID == resourceID {
+			return result, nil
+		}
+	}
+
+	return scanResult{}, Errorf("expected a scanned resource with ID %s, got %+v", resourceID, r)
+}
+
+// assertCompliance checks the compliance tag and the issues of a result. Every expected issue must be
+// reported, and a compliant result must not have any issue.
+func (r scanResult) assertCompliance(complianceTag string, expectedIssues ...string) error {
+	if r.ComplianceTag != complianceTag {
+		return Errorf("expected %s to be %s, got %s with the issues %v",
+			r.ResourceID, complianceTag, r.ComplianceTag, r.Issues)
+	}
+
+	for _, expected := range expectedIssues {
+		if !slices.Contains(r.Issues, expected) {
+			return Errorf("expected %s to have the issue %q, got %v", r.ResourceID, expected, r.Issues)
+		}
+	}
+
+	return nil
+}
+
+// newInspectorWithStandIn returns the module, configured to reach the local AWS stand-in at the given
+// endpoint with the given configuration. The stand-in accepts any static keys, which are used unless
+// another source of credentials is set in the options. The region defaults to the test region.
+func (m *Tests) newInspectorWithStandIn(
+	endpoint string,
+	config *dagger.File,
+	opts dagger.AwsTagInspectorOpts,
+) *dagger.AwsTagInspector {
+	opts.Config = config
+	opts.AwsEndpoint = endpoint
+
+	if opts.AwsRegion == "" {
+		opts.AwsRegion = awsTestRegion
+	}
+
+	if opts.AwsWebIdentityToken == nil && opts.AwsCredentialsFile == nil && opts.AwsConfigFile == nil {
+		opts.AwsAccessKeyID = dag.SetSecret("aws-access-key-id", "test")
+		opts.AwsSecretAccessKey = dag.SetSecret("aws-secret-access-key", "test")
+	}
+
+	return dag.AwsTagInspector(opts)
+}
+
+// scanWithStandIn scans the resources of the local AWS stand-in, and returns the parsed JSON report.
+func (m *Tests) scanWithStandIn(ctx context.Context, inspector *dagger.AwsTagInspector) (scanReport, error) {
+	contents, err := inspector.
+		Scan().
+		Contents(ctx)
+
+	if err != nil {
+		return scanReport{}, WrapError(err, "failed to scan the resources of the local AWS stand-in")
+	}
+
+	var report scanReport
+	if err := json.Unmarshal([]byte(contents), &report); err != nil {
+		return scanReport{}, WrapErrorf(err, "failed to parse the scan report: %s", contents)
+	}
+
+	return report, nil
+}
+
+// runInstanceCmd returns the AWS CLI command that runs an instance with the given tags, in the
+// shorthand syntax, e.g. "{Key=Name,Value=web}".
+func runInstanceCmd(tags string) []string {
+	return runInstanceWithTagSpecificationsCmd("ResourceType=instance,Tags=[" + tags + "]")
+}
+
+// runInstanceWithTagSpecificationsCmd returns the AWS CLI command that runs an instance with the
+// given tag specifications, e.g. as JSON when some values contain commas.
+func runInstanceWithTagSpecificationsCmd(tagSpecifications string) []string {
+	return []string{
+		"ec2", "run-instances", "--image-id", awsTestAMI, "--instance-type", "t3.micro", "--count", "1",
+		"--tag-specifications", tagSpecifications,
+	}
+}
+
+// TestScanEC2InstancesWithLocalStandIn tests the EC2 scanner against a local AWS stand-in.
+//
+// This method creates a compliant instance, a non-compliant one and an excluded bastion host,
+// and verifies that the scan results report the first two, with their state and instance type,
+// and the bastion host as excluded.
+//
+// Arguments:
+// - ctx (context.Context): The context for the test execution.
+//
+// Returns:
+// - error: Returns an error if the scan fails, or if the results aren't the expected ones.
+func (m *Tests) TestScanEC2InstancesWithLocalStandIn(ctx context.Context) error {
+	svc, endpoint, err := m.newAWSStandIn(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.seedAWSStandIn(ctx, svc, [][]string{
+		runInstanceCmd("{Key=Name,Value=web},{Key=Environment,Value=production},{Key=Owner,Value=team@company.com}"),
+		runInstanceCmd("{Key=Name,Value=worker},{Key=Environment,Value=production}"),
+		runInstanceCmd("{Key=Name,Value=bastion-1}"),
+	}); err != nil {
+		return err
+	}
+
+	report, err := m.scanWithStandIn(ctx, m.newInspectorWithStandIn(endpoint,
+		m.TestDir.File("configs/ec2-instances.yaml"), dagger.AwsTagInspectorOpts{}))
+	if err != nil {
+		return err
+	}
+
+	if report.TotalResources != 2 {
+		return Errorf("expected 2 scanned instances, got %d", report.TotalResources)
+	}
+
+	web, err := report.resultWithTag("Name", "web")
+	if err != nil {
+		return err
+	}
+
+	if err := web.assertCompliance("compliant"); err != nil {
+		return err
+	}
+
+	worker, err := report.resultWithTag("Name", "worker")
+	if err != nil {
+		return err
+	}
+
+	if err := worker.assertCompliance("non-compliant", "Missing required tag: Owner"); err != nil {
+		return err
+	}
+
+	if worker.Metadata["InstanceType"] != "t3.micro" || worker.Metadata["State"] != "running" {
+		return Errorf("expected the metadata of a running t3.micro instance, got %v", worker.Metadata)
+	}
+
+	// The bastion host is reported in the excluded section only, with the reason of its exclusion
+	if len(report.Excluded) != 1 || report.Excluded[0].Tags["Name"] != "bastion-1" ||
+		report.Excluded[0].ExclusionReason != "Bastion hosts managed by security team" {
+		return Errorf("expected the bastion host to be reported as excluded, got %+v", report.Excluded)
+	}
+
+	return nil
+}
+
+// TestScanWithTaggingAPIWithLocalStandIn tests the generic scanner, built on the Resource Groups
+// Tagging API, against a local AWS stand-in.
+//
+// This method enables a resource type by its tagging API type name, and verifies that the tagged
+// resources are mapped from their ARNs and scanned against the tag criteria.
+//
+// Arguments:
+// - ctx (context.Context): The context for the test execution.
+//
+// Returns:
+// - error: Returns an error if the scan fails, or if the results aren't the expected ones.
+func (m *Tests) TestScanWithTaggingAPIWithLocalStandIn(ctx context.Context) error {
+	svc, endpoint, err := m.newAWSStandIn(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.seedAWSStandIn(ctx, svc, [][]string{
+		runInstanceCmd("{Key=Environment,Value=staging}"),
+	}); err != nil {
+		return err
+	}
+
+	report, err := m.scanWithStandIn(ctx, m.newInspectorWithStandIn(endpoint,
+		m.TestDir.File("configs/tagging-api.yaml"), dagger.AwsTagInspectorOpts{}))
+	if err != nil {
+		return err
+	}
+
+	instance, err := report.resultWithTag("Environment", "staging")
+	if err != nil {
+		return err
+	}
+
+	if instance.ResourceType != "ec2:instance" || instance.Metadata["Source"] != "tagging-api" {
+		return Errorf("expected an ec2:instance mapped from the tagging API, got %+v", instance)
+	}
+
+	return instance.assertCompliance("non-compliant", "Missing required tag: Owner")
+}
+
+// TestScanMultipleRegionsWithLocalStandIn tests that the scanners run in every configured region,
+// against a local AWS stand-in.
+//
+// This method creates an instance in two regions, and verifies that both are reported in a single
+// report, with the compliance counts of each region in the summary.
+//
+// Arguments:
+// - ctx (context.Context): The context for the test execution.
+//
+// Returns:
+// - error: Returns an error if the scan fails, or if the results aren't the expected ones.
+func (m *Tests) TestScanMultipleRegionsWithLocalStandIn(ctx context.Context) error {
+	svc, endpoint, err := m.newAWSStandIn(ctx)
+	if err != nil {
+		return err
+	}
+
+	regionalInstance := func(region, name string) []string {
+		return append(runInstanceCmd("{Key=Name,Value="+name+"},{Key=Environment,Value=production}"),
+			"--region", region)
+	}
+
+	if err := m.seedAWSStandIn(ctx, svc, [][]string{
+		regionalInstance(awsTestRegion, "east"),
+		regionalInstance(awsTestSecondRegion, "west"),
+	}); err != nil {
+		return err
+	}
+
+	report, err := m.scanWithStandIn(ctx, m.newInspectorWithStandIn(endpoint,
+		m.TestDir.File("configs/ec2-instances.yaml"), dagger.AwsTagInspectorOpts{
+			AwsRegions: []string{awsTestRegion, awsTestSecondRegion},
+		}))
+	if err != nil {
+		return err
+	}
+
+	if report.TotalResources != 2 {
+		return Errorf("expected 2 scanned instances, got %d", report.TotalResources)
+	}
+
+	for name, region := range map[string]string{"east": awsTestRegion, "west": awsTestSecondRegion} {
+		instance, err := report.resultWithTag("Name", name)
+		if err != nil {
+			return err
+		}
+
+		if instance.Region != region {
+			return Errorf("expected the instance %s to be in %s, got %s", name, region, instance.Region)
+		}
+
+		if report.Regions[region].TotalResources != 1 {
+			return Errorf("expected 1 instance in the summary of %s, got %+v", region, report.Regions)
+		}
+	}
+
+	return nil
+}
+
+// TestScanMultipleAccountsWithLocalStandIn tests that the full scan runs in every member account,
+// with the role assumed through STS, against a local AWS stand-in.
+//
+// This method creates an instance in two member accounts, and verifies that each result is labelled
+// with its account ID, and that the summary holds the compliance counts of each account.
+//
+// Arguments:
+// - ctx (context.Context): The context for the test execution.
+//
+// Returns:
+// - error: Returns an error if the scan fails, or if the results aren't the expected ones.
+func (m *Tests) TestScanMultipleAccountsWithLocalStandIn(ctx context.Context) error {
+	svc, endpoint, err := m.newAWSStandIn(ctx)
+	if err != nil {
+		return err
+	}
+
+	accounts := map[string]string{
+		"111111111111": "{Key=Environment,Value=production},{Key=Owner,Value=team@company.com}",
+		"222222222222": "{Key=Environment,Value=production}",
+	}
+
+	for accountID, tags := range accounts {
+		if err := m.seedAWSStandInAccount(ctx, svc, accountID, "TagInspectorRole", [][]string{
+			runInstanceCmd(tags),
+		}); err != nil {
+			return err
+		}
+	}
+
+	report, err := m.scanWithStandIn(ctx, m.newInspectorWithStandIn(endpoint,
+		m.TestDir.File("configs/multi-account.yaml"), dagger.AwsTagInspectorOpts{}))
+	if err != nil {
+		return err
+	}
+
+	if report.TotalResources != 2 {
+		return Errorf("expected 2 scanned instances, got %d", report.TotalResources)
+	}
+
+	expectedCompliance := map[string]string{
+		"111111111111": "compliant",
+		"222222222222": "non-compliant",
+	}
+
+	for _, result := range report.results() {
+		complianceTag, known := expectedCompliance[result.AccountID]
+		if !known {
+			return Errorf("expected every result to be labelled with a member account, got %+v", result)
+		}
+
+		if err := result.assertCompliance(complianceTag); err != nil {
+			return err
+		}
+	}
+
+	for accountID := range accounts {
+		if report.Accounts[accountID].TotalResources != 1 {
+			return Errorf("expected 1 instance in the summary of account %s, got %+v", accountID, report.Accounts)
+		}
+	}
+
+	return nil
+}
+
+// TestScanWithWebIdentityWithLocalStandIn tests the scan with the credentials of a role assumed with
+// a web identity token, against a local AWS stand-in.
+//
+// This method creates an instance in the account of the role, and verifies that it's scanned without
+// any static key passed to the module.
+//
+// Arguments:
+// - ctx (context.Context): The context for the test execution.
+//
+// Returns:
+// - error: Returns an error if the scan fails, or if the results aren't the expected ones.
+func (m *Tests) TestScanWithWebIdentityWithLocalStandIn(ctx context.Context) error {
+	svc, endpoint, err := m.newAWSStandIn(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.seedAWSStandInAccount(ctx, svc, "111111111111", "ci-runner", [][]string{
+		runInstanceCmd("{Key=Environment,Value=production}"),
+	}); err != nil {
+		return err
+	}
+
+	report, err := m.scanWithStandIn(ctx, m.newInspectorWithStandIn(endpoint,
+		m.TestDir.File("configs/ec2-instances.yaml"), dagger.AwsTagInspectorOpts{
+			AwsWebIdentityToken: dag.SetSecret("aws-web-identity-token", "header.payload.signature"),
+			AwsRoleArn:          "arn:aws:iam::111111111111:role/ci-runner",
+		}))
+	if err != nil {
+		return err
+	}
+
+	results := report.results()
+	if len(results) != 1 || !strings.Contains(results[0].ARN, ":111111111111:instance/") {
+		return Errorf("expected the instance of the role's account to be scanned, got %+v", results)
+	}
+
+	return nil
+}
+
+// TestScanWithSharedCredentialsProfileWithLocalStandIn tests the scan with a profile of a mounted
+// shared credentials file, against a local AWS stand-in.
+//
+// Arguments:
+// - ctx (context.Context): The context for the test execution.
+//
+// Returns:
+// - error: Returns an error if the scan fails, or if the results aren't the expected ones.
+func (m *Tests) TestScanWithSharedCredentialsProfileWithLocalStandIn(ctx context.Context) error {
+	svc, endpoint, err := m.newAWSStandIn(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.seedAWSStandIn(ctx, svc, [][]string{
+		runInstanceCmd("{Key=Environment,Value=production}"),
+	}); err != nil {
+		return err
+	}
+
+	credentialsFile := dag.
+		Directory().
+		WithNewFile("credentials", "[ci]\naws_access_key_id = test\naws_secret_access_key = test\n").
+		File("credentials")
+
+	report, err := m.scanWithStandIn(ctx, m.newInspectorWithStandIn(endpoint,
+		m.TestDir.File("configs/ec2-instances.yaml"), dagger.AwsTagInspectorOpts{
+			AwsCredentialsFile: credentialsFile,
+			AwsProfile:         "ci",
+		}))
+	if err != nil {
+		return err
+	}
+
+	if report.TotalResources != 1 {
+		return Errorf("expected the instance to be scanned, got %d resources", report.TotalResources)
+	}
+
+	return nil
+}
+
+// TestScanTagValuesWithLocalStandIn tests that the tag values are validated against the allowed values
+// and the pattern rules of the configuration, against a local AWS stand-in.
+//
+// This method creates an instance with an unknown environment and a malformed owner, and verifies that
+// both are reported as issues that cite their rule.
+//
+// Arguments:
+// - ctx (context.Context): The context for the test execution.
+//
+// Returns:
+// - error: Returns an error if the scan fails, or if the results aren't the expected ones.
+func (m *Tests) TestScanTagValuesWithLocalStandIn(ctx context.Context) error {
+	svc, endpoint, err := m.newAWSStandIn(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.seedAWSStandIn(ctx, svc, [][]string{
+		runInstanceCmd("{Key=Environment,Value=prd},{Key=Owner,Value=bob}"),
+	}); err != nil {
+		return err
+	}
+
+	report, err := m.scanWithStandIn(ctx, m.newInspectorWithStandIn(endpoint,
+		m.TestDir.File("configs/tag-validation.yaml"), dagger.AwsTagInspectorOpts{}))
+	if err != nil {
+		return err
+	}
+
+	instance, err := report.resultWithTag("Owner", "bob")
+	if err != nil {
+		return err
+	}
+
+	return instance.assertCompliance("non-compliant",
+		"Invalid tag value: Environment=prd is not one of [production, staging] "+
+			"(rule: tag_validation.allowed_values.Environment)",
+		`Malformed tag value: Owner=bob does not match ^[a-z0-9._%+-]+@company\.com$ `+
+			"(rule: tag_validation.pattern_rules.Owner)")
+}
+
+// TestScanS3ExclusionsWithLocalStandIn tests that the excluded buckets are skipped, and reported in the
+// excluded section with their reason, against a local AWS stand-in.
+//
+// This method creates a state bucket (excluded with a glob), a log bucket (excluded with a regular
+// expression) and an application bucket, and verifies that only the latter is scanned.
+//
+// Arguments:
+// - ctx (context.Context): The context for the test execution.
+//
+// Returns:
+// - error: Returns an error if the scan fails, or if the results aren't the expected ones.
+func (m *Tests) TestScanS3ExclusionsWithLocalStandIn(ctx context.Context) error {
+	svc, endpoint, err := m.newAWSStandIn(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.seedAWSStandIn(ctx, svc, [][]string{
+		{"s3api", "create-bucket", "--bucket", "terraform-state-prod"},
+		{"s3api", "create-bucket", "--bucket", "log-archive-2024"},
+		{"s3api", "create-bucket", "--bucket", "app-data"},
+		{
+			"s3api", "put-bucket-tagging", "--bucket", "app-data",
+			"--tagging", "TagSet=[{Key=Environment,Value=production}]",
+		},
+	}); err != nil {
+		return err
+	}
+
+	report, err := m.scanWithStandIn(ctx, m.newInspectorWithStandIn(endpoint,
+		m.TestDir.File("configs/s3-exclusions.yaml"), dagger.AwsTagInspectorOpts{}))
+	if err != nil {
+		return err
+	}
+
+	if report.TotalResources != 1 {
+		return Errorf("expected only the application bucket to be scanned, got %d resources", report.TotalResources)
+	}
+
+	if _, err := report.resultWithID("app-data"); err != nil {
+		return err
+	}
+
+	exclusionReasons := map[string]string{}
+	for _, excluded := range report.Excluded {
+		exclusionReasons[excluded.ResourceID] = excluded.ExclusionReason
+	}
+
+	for bucket, reason := range map[string]string{
+		"terraform-state-prod": "Terraform state buckets managed separately",
+		"log-archive-2024":     "Logging buckets excluded from standard compliance",
+	} {
+		if exclusionReasons[bucket] != reason {
+			return Errorf("expected %s to be excluded with the reason %q, got %+v", bucket, reason, report.Excluded)
+		}
+	}
+
+	return nil
+}
+
+// TestScanS3BucketTagsWithLocalStandIn tests that the S3 scanner checks the tags of each bucket, and
+// the compliance level set per bucket with the ComplianceLevel tag, against a local AWS stand-in.
+//
+// This method creates a bucket with every required tag, and a bucket whose ComplianceLevel tag requires
+// an extra tag, and verifies that only the latter is reported as non-compliant.
+//
+// Arguments:
+// - ctx (context.Context): The context for the test execution.
+//
+// Returns:
+// - error: Returns an error if the scan fails, or if the results aren't the expected ones.
+func (m *Tests) TestScanS3BucketTagsWithLocalStandIn(ctx context.Context) error {
+	svc, endpoint, err := m.newAWSStandIn(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.seedAWSStandIn(ctx, svc, [][]string{
+		{"s3api", "create-bucket", "--bucket", "app-data"},
+		{
+			"s3api", "put-bucket-tagging", "--bucket", "app-data",
+			"--tagging", "TagSet=[{Key=Environment,Value=production},{Key=Owner,Value=data-team}]",
+		},
+		{"s3api", "create-bucket", "--bucket", "audit-logs"},
+		{
+			"s3api", "put-bucket-tagging", "--bucket", "audit-logs",
+			"--tagging", "TagSet=[{Key=Environment,Value=production},{Key=Owner,Value=security-team}," +
+				"{Key=ComplianceLevel,Value=high}]",
+		},
+	}); err != nil {
+		return err
+	}
+
+	report, err := m.scanWithStandIn(ctx, m.newInspectorWithStandIn(endpoint,
+		m.TestDir.File("configs/s3-bucket-tags.yaml"), dagger.AwsTagInspectorOpts{}))
+	if err != nil {
+		return err
+	}
+
+	appData, err := report.resultWithID("app-data")
+	if err != nil {
+		return err
+	}
+
+	if err := appData.assertCompliance("compliant"); err != nil {
+		return err
+	}
+
+	auditLogs, err := report.resultWithID("audit-logs")
+	if err != nil {
+		return err
+	}
+
+	return auditLogs.assertCompliance("non-compliant", "Missing required tag (compliance level): DataClassification")
+}
+
+// TestScanTagRulesWithLocalStandIn tests that the conditional tagging rules of the configuration are
+// evaluated per resource, against a local AWS stand-in.
+//
+// This method creates a production instance that breaks every rule, and a development instance
+// that the conditional rules don't apply to, and verifies that the issues cite the rule IDs.
+//
+// Arguments:
+// - ctx (context.Context): The context for the test execution.
+//
+// Returns:
+// - error: Returns an error if the scan fails, or if the results aren't the expected ones.
+func (m *Tests) TestScanTagRulesWithLocalStandIn(ctx context.Context) error {
+	svc, endpoint, err := m.newAWSStandIn(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The tags are passed as JSON, since some values contain commas.
+	instanceWithTags := func(tags string) []string {
+		return runInstanceWithTagSpecificationsCmd(`[{"ResourceType":"instance","Tags":[` + tags + `]}]`)
+	}
+
+	if err := m.seedAWSStandIn(ctx, svc, [][]string{
+		instanceWithTags(`{"Key":"Environment","Value":"prod"},{"Key":"CostCenter","Value":"CC-9"},` +
+			`{"Key":"TeamCostCenters","Value":"CC-1,CC-2"},{"Key":"cost_owner","Value":"finance"}`),
+		instanceWithTags(`{"Key":"Environment","Value":"development"},{"Key":"CostCenter","Value":"CC-1"},` +
+			`{"Key":"TeamCostCenters","Value":"CC-1"}`),
+	}); err != nil {
+		return err
+	}
+
+	report, err := m.scanWithStandIn(ctx, m.newInspectorWithStandIn(endpoint,
+		m.TestDir.File("configs/tag-rules.yaml"), dagger.AwsTagInspectorOpts{}))
+	if err != nil {
+		return err
+	}
+
+	production, err := report.resultWithTag("Environment", "prod")
+	if err != nil {
+		return err
+	}
+
+	if err := production.assertCompliance("non-compliant",
+		"Rule violation: missing required tag DataClassification (rule: rules.prod-data-protection)",
+		"Rule violation: CostCenter=CC-9 is not one of [CC-1, CC-2] (rule: rules.cost-center-per-team)",
+		"Rule violation: tag key cost_owner is not PascalCase (rule: rules.pascal-case-keys)"); err != nil {
+		return err
+	}
+
+	development, err := report.resultWithTag("Environment", "development")
+	if err != nil {
+		return err
+	}
+
+	return development.assertCompliance("compliant")
+}
+
+// TestScanReportFormatsWithLocalStandIn tests that the scan results can be rendered as CSV, Markdown,
+// HTML and SARIF reports, against a local AWS stand-in.
+//
+// This method creates a compliant instance, a non-compliant one and an excluded bastion host,
+// and verifies that every report has the expected file name and reports the same resources.
+//
+// Arguments:
+// - ctx (context.Context): The context for the test execution.
+//
+// Returns:
+// - error: Returns an error if the scan fails, or if a report isn't the expected one.
+func (m *Tests) TestScanReportFormatsWithLocalStandIn(ctx context.Context) error {
+	svc, endpoint, err := m.newAWSStandIn(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.seedAWSStandIn(ctx, svc, [][]string{
+		runInstanceCmd("{Key=Name,Value=web},{Key=Environment,Value=production},{Key=Owner,Value=team@company.com}"),
+		runInstanceCmd("{Key=Name,Value=worker},{Key=Environment,Value=production}"),
+		runInstanceCmd("{Key=Name,Value=bastion-1}"),
+	}); err != nil {
+		return err
+	}
+
+	inspector := m.newInspectorWithStandIn(endpoint,
+		m.TestDir.File("configs/ec2-instances.yaml"), dagger.AwsTagInspectorOpts{})
+
+	for _, report := range []struct {
+		format   string
+		fileName string
+		expected []string
+	}{
+		{
+			format:   "csv",
+			fileName: "scan-results.csv",
+			expected: []string{
+				"account_id,region,resource_type,resource_id,arn,compliance,issues,exclusion_reason,tags",
+				"non-compliant,Missing required tag: Owner",
+				"excluded,,Bastion hosts managed by security team",
+			},
+		},
+		{
+			format:   "markdown",
+			fileName: "scan-results.md",
+			expected: []string{
+				"| 2 | 1 | 1 | 1 |",
+				"## Non-compliant resources",
+				"## Excluded resources",
+			},
+		},
+		{
+			format:   "html",
+			fileName: "scan-results.html",
+			expected: []string{
+				`<select id="filter-compliance"`,
+				`data-compliance="non-compliant"`,
+				"Missing required tag: Owner",
+			},
+		},
+		{
+			format:   "sarif",
+			fileName: "scan-results.sarif",
+			expected: []string{
+				`"version": "2.1.0"`,
+				`"ruleId": "required-tags"`,
+				`"fullyQualifiedName": "arn:aws:ec2:us-east-1:`,
+				`/us-east-1/ec2:instance/i-`,
+			},
+		},
+	} {
+		file := inspector.Scan(dagger.AwsTagInspectorScanOpts{Format: report.format})
+
+		fileName, nameErr := file.Name(ctx)
+		if nameErr != nil {
+			return WrapErrorf(nameErr, "failed to render the %s report", report.format)
+		}
+
+		if fileName != report.fileName {
+			return Errorf("expected the %s report to be named %s, got %s", report.format, report.fileName, fileName)
+		}
+
+		contents, contentsErr := file.Contents(ctx)
+		if contentsErr != nil {
+			return WrapErrorf(contentsErr, "failed to read the %s report", report.format)
+		}
+
+		for _, expected := range report.expected {
+			if !strings.Contains(contents, expected) {
+				return Errorf("expected the %s report to contain %s, got %s", report.format, expected, contents)
+			}
+		}
+	}
+
+	return nil
+}
+
+// remediationReport is the JSON plan of a remediation, or its outcome once applied, as written by Remediate.
+type remediationReport struct {
+	DryRun  bool `json:"dry_run"`
+	Summary struct {
+		Resources  int `json:"resources"`
+		Changes    int `json:"changes"`
+		Applied    int `json:"applied"`
+		Failed     int `json:"failed"`
+		Unresolved int `json:"unresolved"`
+	} `json:"summary"`
+	Resources []remediatedResource `json:"resources"`
+}
+
+// remediatedResource holds the tag changes planned for a resource in a remediation report.
+type remediatedResource struct {
+	ResourceID string `json:"resource_id"`
+	Changes    []struct {
+		Key          string `json:"key"`
+		Value        string `json:"value"`
+		CurrentValue string `json:"current_value"`
+		Source       string `json:"source"`
+	} `json:"changes"`
+	Unresolved []string `json:"unresolved"`
+	Status     string   `json:"status"`
+}
+
+// resource returns the planned changes of the resource with the given ID, and an error if there's none.
+func (r remediationReport) resource(resourceID string) (remediatedResource, error) {
+	for _, resource := range r.Resources {
+		if resource.ResourceID == resourceID {
+			return resource, nil
+		}
+	}
+
+	return remediatedResource{}, Errorf("expected a planned resource with ID %s, got %+v", resourceID, r.Resources)
+}
+
+// assertChange checks that the plan sets the tag to the value, from the given source, and that it's
+// the only change of the resource.
+func (r remediatedResource) assertChange(key, currentValue, value, source string) error {
+	if len(r.Changes) != 1 {
+		return Errorf("expected a single change of %s, got %+v", r.ResourceID, r.Changes)
+	}
+
+	change := r.Changes[0]
+	if change.Key != key || change.CurrentValue != currentValue || change.Value != value || change.Source != source {
+		return Errorf("expected %s to be set from %q to %q (%s) on %s, got %+v",
+			key, currentValue, value, source, r.ResourceID, change)
+	}
+
+	return nil
+}
+
+// remediateWithStandIn plans (or applies) the remediation of the resources of the local AWS stand-in,
+// and returns the parsed JSON plan.
+func (m *Tests) remediateWithStandIn(
+	ctx context.Context,
+	inspector *dagger.AwsTagInspector,
+	apply bool,
+) (remediationReport, error) {
+	contents, err := inspector.
+		Remediate(dagger.AwsTagInspectorRemediateOpts{Apply: apply}).
+		Contents(ctx)
+
+	if err != nil {
+		return remediationReport{}, WrapError(err, "failed to remediate the resources of the local AWS stand-in")
+	}
+
+	var report remediationReport
+	if err := json.Unmarshal([]byte(contents), &report); err != nil {
+		return remediationReport{}, WrapErrorf(err, "failed to parse the remediation plan: %s", contents)
+	}
+
+	return report, nil
+}
+
+// TestRemediateWithLocalStandIn tests the remediation plan, and that applying it makes the resources
+// compliant, against a local AWS stand-in.
+//
+// This method creates an instance with a wrong ManagedBy value, a bucket missing its Owner tag, and an
+// instance missing the Environment tag, which has no default value. It verifies the dry-run plan, applies
+// it, and verifies that a new scan only reports the instance that can't be fixed automatically.
+//
+// Arguments:
+// - ctx (context.Context): The context for the test execution.
+//
+// Returns:
+// - error: Returns an error if the remediation fails, or if the results aren't the expected ones.
+func (m *Tests) TestRemediateWithLocalStandIn(ctx context.Context) error {
+	svc, endpoint, err := m.newAWSStandIn(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.seedAWSStandIn(ctx, svc, [][]string{
+		runInstanceCmd("{Key=Name,Value=web},{Key=Environment,Value=production},{Key=Owner,Value=web-team}," +
+			"{Key=ManagedBy,Value=console}"),
+		runInstanceCmd("{Key=Name,Value=worker},{Key=Owner,Value=web-team},{Key=ManagedBy,Value=terraform}"),
+		{"s3api", "create-bucket", "--bucket", "app-data"},
+		{
+			"s3api", "put-bucket-tagging", "--bucket", "app-data",
+			"--tagging", "TagSet=[{Key=Environment,Value=production},{Key=ManagedBy,Value=terraform}]",
+		},
+	}); err != nil {
+		return err
+	}
+
+	inspector := m.newInspectorWithStandIn(endpoint,
+		m.TestDir.File("configs/remediation.yaml"), dagger.AwsTagInspectorOpts{})
+
+	// The instances are found by their Name tag, since their IDs are set by the stand-in
+	before, err := m.scanWithStandIn(ctx, inspector)
+	if err != nil {
+		return err
+	}
+
+	web, err := before.resultWithTag("Name", "web")
+	if err != nil {
+		return err
+	}
+
+	worker, err := before.resultWithTag("Name", "worker")
+	if err != nil {
+		return err
+	}
+
+	plan, err := m.remediateWithStandIn(ctx, inspector, false)
+	if err != nil {
+		return err
+	}
+
+	if !plan.DryRun || plan.Summary.Resources != 3 || plan.Summary.Unresolved != 1 {
+		return Errorf("expected a dry-run plan of 3 resources with 1 unresolved, got %+v", plan.Summary)
+	}
+
+	webPlan, err := plan.resource(web.ResourceID)
+	if err != nil {
+		return err
+	}
+
+	if err := webPlan.assertChange("ManagedBy", "console", "terraform", "specific_tags"); err != nil {
+		return err
+	}
+
+	bucketPlan, err := plan.resource("app-data")
+	if err != nil {
+		return err
+	}
+
+	if err := bucketPlan.assertChange("Owner", "", "platform-team", "remediation.default_values"); err != nil {
+		return err
+	}
+
+	workerPlan, err := plan.resource(worker.ResourceID)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Equal(workerPlan.Unresolved, []string{"Environment"}) {
+		return Errorf("expected the Environment tag of the worker to be unresolved, got %+v", workerPlan)
+	}
+
+	applied, err := m.remediateWithStandIn(ctx, inspector, true)
+	if err != nil {
+		return err
+	}
+
+	if applied.DryRun || applied.Summary.Applied != 2 || applied.Summary.Failed != 0 {
+		return Errorf("expected 2 resources to be remediated without failures, got %+v", applied.Summary)
+	}
+
+	// Only the instance missing the Environment tag is still non-compliant
+	after, err := m.scanWithStandIn(ctx, inspector)
+	if err != nil {
+		return err
+	}
+
+	if after.Compliance.Compliant != 2 || after.Compliance.NonCompliant != 1 {
+		return Errorf("expected 2 compliant resources and 1 non-compliant, got %+v", after.Compliance)
+	}
+
+	bucket, err := after.resultWithID("app-data")
+	if err != nil {
+		return err
+	}
+
+	if bucket.Tags["Owner"] != "platform-team" {
+		return Errorf("expected the bucket to be tagged Owner=platform-team, got %v", bucket.Tags)
+	}
+
+	remediatedWorker, err := after.resultWithID(worker.ResourceID)
+	if err != nil {
+		return err
+	}
+
+	return remediatedWorker.assertCompliance("non-compliant", "Missing required tag: Environment")
+}
+
+// newLocalService starts a local service, and returns it with its endpoint on the given port.
+func (m *Tests) newLocalService(
+	ctx context.Context,
+	ctr *dagger.Container,
+	port int,
+	scheme string,
+) (*dagger.Service, string, error) {
+	svc, svcErr := ctr.
+		AsService().
+		Start(ctx)
+
+	if svcErr != nil {
+		return nil, "", WrapError(svcErr, "failed to start the local service")
+	}
+
+	endpoint, endpointErr := svc.Endpoint(ctx, dagger.ServiceEndpointOpts{
+		Port:   port,
+		Scheme: scheme,
+	})
+
+	if endpointErr != nil {
+		return nil, "", WrapError(endpointErr, "failed to get the endpoint of the local service")
+	}
+
+	return svc, endpoint, nil
+}
+
+// getFromLocalService parses the JSON body of a GET request to a local service into the target.
+// The request is never cached, since it reads the state of the service.
+func (m *Tests) getFromLocalService(
+	ctx context.Context,
+	svc *dagger.Service,
+	port int,
+	path string,
+	target any,
+) error {
+	body, err := dag.
+		Container().
+		From(curlImage).
+		WithServiceBinding("local", svc).
+		WithEnvVariable("CACHE_BUSTER", time.Now().String()).
+		WithExec([]string{"curl", "-sSf", "http://local:" + strconv.Itoa(port) + path}).
+		Stdout(ctx)
+
+	if err != nil {
+		return WrapErrorf(err, "failed to get %s from the local service", path)
+	}
+
+	if err := json.Unmarshal([]byte(body), target); err != nil {
+		return WrapErrorf(err, "failed to parse the response of %s: %s", path, body)
+	}
+
+	return nil
+}
+
+// webhookRequest is a request recorded by the Slack webhook stand-in.
+type webhookRequest struct {
+	Path string `json:"path"`
+	Body struct {
+		Text    string `json:"text"`
+		Channel string `json:"channel"`
+	} `json:"body"`
+}
+
+// smtpSinkMessages are the emails received by the SMTP sink, as listed by its API.
+type smtpSinkMessages struct {
+	Messages []struct {
+		Subject string `json:"Subject"`
+		From    struct {
+			Address string `json:"Address"`
+		} `json:"From"`
+		To []struct {
+			Address string `json:"Address"`
+		} `json:"To"`
+	} `json:"messages"`
+}
+
+// TestNotifyWithLocalStandIns tests that the summary of a scan is posted once to the Slack webhook
+// and emailed to the recipients of the configuration, against local stand-ins of AWS, of the Slack
+// webhooks and of an SMTP server.
+//
+// This method scans a non-compliant instance, notifies with the templates of the configuration, and
+// verifies the messages received by the webhook stand-in and the email received by the SMTP sink.
+//
+// Arguments:
+// - ctx (context.Context): The context for the test execution.
+//
+// Returns:
+// - error: Returns an error if the notification fails, or if the messages aren't the expected ones.
+func (m *Tests) TestNotifyWithLocalStandIns(ctx context.Context) error {
+	awsSvc, awsEndpoint, err := m.newAWSStandIn(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.seedAWSStandIn(ctx, awsSvc, [][]string{
+		runInstanceCmd("{Key=Name,Value=worker}"),
+	}); err != nil {
+		return err
+	}
+
+	webhookSvc, webhookEndpoint, err := m.newLocalService(ctx, dag.
+		Container().
+		From(webhookStandInImage).
+		WithNewFile("/srv/webhook.py", webhookStandInScript).
+		WithExposedPort(webhookStandInPort).
+		WithExec([]string{"python", "/srv/webhook.py"}), webhookStandInPort, "http")
+	if err != nil {
+		return err
+	}
+
+	smtpSvc, smtpEndpoint, err := m.newLocalService(ctx, dag.
+		Container().
+		From(smtpSinkImage).
+		WithExposedPort(smtpSinkPort).
+		WithExposedPort(smtpSinkAPIPort), smtpSinkPort, "")
+	if err != nil {
+		return err
+	}
+
+	smtpHost, _, err := net.SplitHostPort(smtpEndpoint)
+	if err != nil {
+		return WrapErrorf(err, "unexpected endpoint of the local SMTP sink: %s", smtpEndpoint)
+	}
+
+	cfg, cfgErr := m.TestDir.File("configs/notifications.yaml").Contents(ctx)
+	if cfgErr != nil {
+		return WrapError(cfgErr, "failed to read the notifications configuration")
+	}
+
+	cfg = strings.Replace(cfg, "host: localhost", "host: "+smtpHost, 1)
+
+	inspector := m.newInspectorWithStandIn(awsEndpoint,
+		dag.Directory().WithNewFile("notifications.yaml", cfg).File("notifications.yaml"),
+		dagger.AwsTagInspectorOpts{})
+
+	notified, notifyErr := inspector.
+		Notify(ctx, dagger.AwsTagInspectorNotifyOpts{
+			ScanResults:     inspector.Scan(),
+			SlackWebhookURL: dag.SetSecret("slack-webhook-url", webhookEndpoint+"/services/T000/B000/XXXX"),
+		})
+
+	if notifyErr != nil {
+		return WrapError(notifyErr, "failed to send the notifications")
+	}
+
+	if notified != "notified the Slack webhook and 1 email recipient(s)" {
+		return Errorf("unexpected notification result: %s", notified)
+	}
+
+	var requests []webhookRequest
+	if err := m.getFromLocalService(ctx, webhookSvc, webhookStandInPort, "/requests", &requests); err != nil {
+		return err
+	}
+
+	if len(requests) != 1 {
+		return Errorf("expected a single message posted to the Slack webhook, got %+v", requests)
+	}
+
+	request := requests[0]
+	if request.Path != "/services/T000/B000/XXXX" {
+		return Errorf("expected the message to be posted to the webhook path, got %s", request.Path)
+	}
+
+	if request.Body.Channel != "compliance-alerts" {
+		return Errorf("expected the configured channel to be set in the payload, got %q", request.Body.Channel)
+	}
+
+	if !strings.HasPrefix(request.Body.Text, "Tag audit: 1 non-compliant of 1") ||
+		!strings.Contains(request.Body.Text, "Missing required tag: Environment; Missing required tag: Owner") {
+		return Errorf("expected the message to summarize the scan with the top offenders, got %s", request.Body.Text)
+	}
+
+	var emails smtpSinkMessages
+	if err := m.getFromLocalService(ctx, smtpSvc, smtpSinkAPIPort, "/api/v1/messages", &emails); err != nil {
+		return err
+	}
+
+	if len(emails.Messages) != 1 {
+		return Errorf("expected a single email, got %+v", emails.Messages)
+	}
+
+	email := emails.Messages[0]
+	if email.Subject != "Tag audit: 1 non-compliant resource(s)" ||
+		email.From.Address != "tag-inspector@company.com" ||
+		len(email.To) != 1 || email.To[0].Address != "cloud-team@company.com" {
+		return Errorf("unexpected email: %+v", email)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"path/filepath"
+	"strings"
 
 	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
 	"github.com/Excoriate/daggerx/pkg/apkox"
@@ -11,6 +12,14 @@ import (
 const (
 	// Path to the APKO configuration preset for Alpine base image.
 	configPresetAlpinePath = "config/presets/base-alpine.yaml"
+	// Path to the APKO configuration preset for Wolfi base image.
+	configPresetWolfiPath = "config/presets/base-wolfi.yaml"
+	// Name of the APKO preset file, once mounted into the builder container.
+	apkoPresetFileName = "apko-preset.yaml"
+	// Directory where APKO writes the SBOM of the built image.
+	apkoSbomDir      = "sbom"
+	apkoDistroAlpine = "alpine"
+	apkoDistroWolfi  = "wolfi"
 	// Name of the output tar file generated by APKO.
 	apkoOutputTar = "image.tar"
 	// apkoRepositoryURL is the URL of the APKO repository.
@@ -36,25 +45,55 @@ func (m *Terragrunt) Base(imageURL string) *Terragrunt {
 // BaseApko sets up a base container using an APKO preset configuration.
 //
 // This function performs the following steps:
-// 1. Retrieves keyring information for the given preset.
-// 2. Obtains the APKO configuration file path.
-// 3. Sets up the APKO cache directory.
-// 4. Retrieves the Alpine key to be mounted into the container.
-// 5. Builds the APKO command with the specified parameters.
-// 6. Creates and decorates the container with APKO-related mounts and executes the APKO build command.
+// 1. Resolves the APKO preset file, either the one passed or the embedded preset of the distro.
+// 2. Sets up the APKO cache directory.
+// 3. Validates and mounts the extra keyrings into the builder container.
+// 4. Builds the APKO command with the extra packages, repositories and keyrings.
+// 5. Creates and decorates the container with APKO-related mounts and executes the APKO build command.
+// 6. Keeps the generated SBOM, which is exposed in the ApkoSbom field.
 //
 // Parameters:
-// - preset: A string representing the APKO preset to be used.
+// - extraPackages: Extra packages to install with APKO. Optional parameter.
+// - presetFile: A custom APKO preset file, which takes precedence over the distro. Optional parameter.
+// - distro: The distro of the embedded preset to use, "alpine" (default) or "wolfi". Optional parameter.
+// - repositories: Extra repositories to append to the preset. Optional parameter.
+// - keyrings: Extra keyrings to append to the preset, in the form path=url. Optional parameter.
 //
 // Returns:
 // - *dagger.Container: A pointer to the created and configured container.
 // - error: An error object if any step fails, otherwise nil.
 // See: https://github.com/Excoriate/daggerx/tree/main/pkg/builderx
-func (m *Terragrunt) BaseApko(extraPackages []string) (*dagger.Container, error) {
-	apkoPresetFileToMount := filepath.Join(fixtures.MntPrefix, configPresetAlpinePath)
-	apkoPresetFile := dag.CurrentModule().
-		Source().
-		File(configPresetAlpinePath)
+func (m *Terragrunt) BaseApko(
+	// extraPackages is a list of extra packages to install with APKO.
+	// +optional
+	extraPackages []string,
+	// presetFile is a custom APKO preset file. If set, the distro is ignored.
+	// +optional
+	presetFile *dagger.File,
+	// distro is the distro of the embedded APKO preset to use, "alpine" or "wolfi". Default is "alpine".
+	// +optional
+	distro string,
+	// repositories is a list of extra repositories to append to the preset.
+	// +optional
+	repositories []string,
+	// keyrings is a list of extra keyrings to append to the preset. They should be provided as path=url.
+	// E.g.: /etc/apk/keys/alpine-devel@lists.alpinelinux.org-4a6a0840.rsa.pub=
+	// https://alpinelinux.org/keys/alpine-devel@lists.alpinelinux.org-4a6a0840.rsa.pub
+	// +optional
+	keyrings []string,
+) (*dagger.Container, error) {
+	if presetFile == nil {
+		presetPath, err := getApkoPresetPath(distro)
+		if err != nil {
+			return nil, err
+		}
+
+		presetFile = dag.CurrentModule().
+			Source().
+			File(presetPath)
+	}
+
+	apkoPresetFileToMount := filepath.Join(fixtures.MntPrefix, apkoPresetFileName)
 
 	// APKO Alpine key to mount into the container.
 	apkoCacheDir := filepath.Join(fixtures.MntPrefix, "var", "cache", "apko")
@@ -64,25 +103,50 @@ func (m *Terragrunt) BaseApko(extraPackages []string) (*dagger.Container, error)
 		NewApkoBuilder().
 		WithConfigFile(apkoPresetFileToMount). // Path of the preset file mounted into the container.
 		WithOutputImage(apkoOutputTar).
-		WithCacheDir(apkoCacheDir)
+		WithCacheDir(apkoCacheDir).
+		WithSBOM(true).
+		WithSBOMPath(apkoSbomDir)
 
 	for _, pkg := range extraPackages {
 		apkoCmdBuider.WithPackageAppend(pkg)
 	}
 
-	apkoBuildCmd, apkoBuildCmdErr := apkoCmdBuider.
-		BuildCommand()
-
-	if apkoBuildCmdErr != nil {
-		return nil, WrapError(apkoBuildCmdErr, "failed to build apko command")
+	for _, repository := range repositories {
+		apkoCmdBuider.WithRepositoryAppend(repository)
 	}
 
 	// Builder container with APKO preset file mounted.
 	builderCtr := dag.
 		Container().
 		From(apkoRepositoryURL).
-		WithMountedFile(apkoPresetFileToMount, apkoPresetFile).
-		WithMountedCache(apkoCacheDir, dag.CacheVolume("apko-cache")) // Create a cache volume for APKO.
+		WithMountedFile(apkoPresetFileToMount, presetFile).
+		WithMountedCache(apkoCacheDir, dag.CacheVolume("apko-cache")). // Create a cache volume for APKO.
+		WithDirectory(apkoSbomDir, dag.Directory())
+
+	if len(keyrings) > 0 {
+		if err := apkox.IsKeyringFormatValid(keyrings, false); err != nil {
+			return nil, WrapError(err, "invalid keyring format, it should be path=url")
+		}
+
+		for _, keyring := range keyrings {
+			keyringInfo, err := apkox.ParseKeyring(keyring)
+			if err != nil {
+				return nil, WrapErrorf(err, "failed to parse keyring: %s", keyring)
+			}
+
+			builderCtr = builderCtr.
+				WithMountedFile(keyringInfo.Path, dag.HTTP(keyringInfo.URL))
+
+			apkoCmdBuider.WithKeyring(keyringInfo.Path)
+		}
+	}
+
+	apkoBuildCmd, apkoBuildCmdErr := apkoCmdBuider.
+		BuildCommand()
+
+	if apkoBuildCmdErr != nil {
+		return nil, WrapError(apkoBuildCmdErr, "failed to build apko command")
+	}
 
 	builderCtr = builderCtr.WithExec(apkoBuildCmd)
 
@@ -94,6 +158,21 @@ func (m *Terragrunt) BaseApko(extraPackages []string) (*dagger.Container, error)
 		Import(outputTar)
 
 	m.Ctr = tgCtr
+	m.ApkoSbom = builderCtr.Directory(apkoSbomDir)
 
 	return tgCtr, nil
 }
+
+// getApkoPresetPath returns the path of the embedded APKO preset for the given distro.
+// If the distro is empty, the Alpine preset is returned.
+func getApkoPresetPath(distro string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(distro)) {
+	case "", apkoDistroAlpine:
+		return configPresetAlpinePath, nil
+	case apkoDistroWolfi:
+		return configPresetWolfiPath, nil
+	default:
+		return "", Errorf("unsupported APKO distro: %s, supported distros are %s and %s",
+			distro, apkoDistroAlpine, apkoDistroWolfi)
+	}
+}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"strings"
 
 	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
@@ -33,9 +34,14 @@ type Terragrunt struct {
 	// ApkoPackages is a list of packages to install with APKO.
 	// +private
 	ApkoPackages []string
+	// ApkoSbom is the directory with the SBOM generated by APKO when the base image is built with it.
+	ApkoSbom *dagger.Directory
 	// TgCmd is the Terragrunt command to execute.
 	// +private
 	Tg *TerragruntCmd
+	// Redaction is the configuration to redact sensitive values from the outputs and logs.
+	// +private
+	Redaction *RedactionConfig
 }
 
 // New creates a new Terragrunt module.
@@ -46,9 +52,18 @@ type Terragrunt struct {
 // - ctr: The container to use as a base container. Optional parameter.
 // - envVarsFromHost: A list of environment variables to pass from the host to the container in a
 // slice of strings. Optional parameter.
+// - apkoPresetFile: A custom APKO preset file to build the base image with. Optional parameter.
+// - apkoDistro: The distro of the embedded APKO preset, "alpine" or "wolfi". Optional parameter.
+// - apkoRepositories: Extra repositories to append to the APKO preset. Optional parameter.
+// - apkoKeyrings: Extra keyrings to append to the APKO preset, in the form path=url. Optional parameter.
+// - versionFilesSource: A directory with the version files to resolve the versions of the IaC tools from.
+// It can't be combined with ctr or imageURL. Optional parameter.
 //
 // Returns a pointer to a Terragrunt instance and an error, if any.
 func New(
+	// ctx is the context to use when reading the version files.
+	// +optional
+	ctx context.Context,
 	// ctr is the container to use as a base container.
 	// +optional
 	ctr *dagger.Container,
@@ -77,6 +92,24 @@ func New(
 	// extraPackages is a list of extra packages to install with APKO, from the Alpine packages repository.
 	// +optional
 	extraPackages []string,
+	// apkoPresetFile is a custom APKO preset file to build the base image with.
+	// +optional
+	apkoPresetFile *dagger.File,
+	// apkoDistro is the distro of the embedded APKO preset, "alpine" or "wolfi". Default is "alpine".
+	// +optional
+	apkoDistro string,
+	// apkoRepositories is a list of extra repositories to append to the APKO preset.
+	// +optional
+	apkoRepositories []string,
+	// apkoKeyrings is a list of extra keyrings to append to the APKO preset, in the form path=url.
+	// +optional
+	apkoKeyrings []string,
+	// versionFilesSource is a directory with the version files (.terragrunt-version, .terraform-version,
+	// .opentofu-version or .tool-versions) to resolve the versions of the IaC tools from. Explicit versions
+	// take precedence over the version files, and tools that aren't pinned are installed with their default version.
+	// It can't be combined with ctr or imageURL.
+	// +optional
+	versionFilesSource *dagger.Directory,
 ) (*Terragrunt, error) {
 	dagModule := &Terragrunt{
 		ApkoPackages: []string{},
@@ -85,7 +118,15 @@ func New(
 			Logs: &LogsConfig{},
 			Opts: &TerragruntOptsConfig{},
 		},
+		Redaction: &RedactionConfig{},
+	}
+	// The version files pin the IaC tools installed in the built-in base image, so they can't be
+	// applied to a provided container or image.
+	if versionFilesSource != nil && (ctr != nil || imageURL != "") {
+		return nil, Errorf("the version files source can't be used with a base container or an image URL, " +
+			"they only pin the IaC tools installed in the built-in base image")
 	}
+
 	// Precedence:
 	// 1. ctr
 	// 2. imageURL
@@ -114,17 +155,27 @@ func New(
 
 		dagModule.Base(imageURL)
 	} else {
-		_, tgCtrErr := dagModule.BaseApko(dagModule.ApkoPackages)
+		_, tgCtrErr := dagModule.BaseApko(
+			dagModule.ApkoPackages,
+			apkoPresetFile,
+			apkoDistro,
+			apkoRepositories,
+			apkoKeyrings)
 		if tgCtrErr != nil {
 			return nil, WrapError(tgCtrErr, "failed to create base image apko")
 		}
 
+		pinnedVersions, versionsErr := resolveIACToolVersions(ctx, versionFilesSource)
+		if versionsErr != nil {
+			return nil, WrapError(versionsErr, "failed to resolve the IaC tools versions from the version files")
+		}
+
 		dagModule.WithTerragruntCacheConfiguration()
 		dagModule.WithTerraformCacheConfiguration()
 		dagModule.WithIACToolsInstalled(
-			handleToolVersions(tgVersion),
-			handleToolVersions(tfVersion),
-			handleToolVersions(openTofuVersion))
+			handleToolVersions(firstNonEmpty(tgVersion, pinnedVersions.Terragrunt)),
+			handleToolVersions(firstNonEmpty(tfVersion, pinnedVersions.Terraform)),
+			handleToolVersions(firstNonEmpty(openTofuVersion, pinnedVersions.OpenTofu)))
 		dagModule.WithTerragruntPermissionsOnDirsDefault()
 	}
 
@@ -161,3 +212,14 @@ func handleToolVersions(version string) string {
 
 	return version
 }
+
+// firstNonEmpty returns the first non-empty value, or an empty string if all of them are empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
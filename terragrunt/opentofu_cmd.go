@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+
+	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
+)
+
+// OpentofuCmd represents a command to be executed by OpenTofu, for plain OpenTofu
+// projects (without terragrunt.hcl files).
+type OpentofuCmd struct {
+	// Tg is the Terragrunt module, which holds the container where the commands are executed.
+	// +private
+	Tg *Terragrunt
+	// Entrypoint is the entrypoint to use when executing the OpenTofu command.
+	Entrypoint string
+}
+
+// Opentofu returns the command runner for plain OpenTofu projects. The commands are validated
+// as OpenTofu commands, and executed with the opentofu binary instead of terragrunt.
+//
+// Returns:
+// - *OpentofuCmd: The command runner for OpenTofu, sharing the container of the module.
+func (m *Terragrunt) Opentofu() *OpentofuCmd {
+	return &OpentofuCmd{
+		Tg:         m,
+		Entrypoint: string(OpentofuTool),
+	}
+}
+
+// getEntrypoint returns the entrypoint to use when executing the OpenTofu command.
+func (c *OpentofuCmd) getEntrypoint() string {
+	return string(OpentofuTool)
+}
+
+// validate checks if the provided command is a recognized opentofu command.
+// It returns an error if the command is invalid or empty, including the terragrunt-only commands.
+func (c *OpentofuCmd) validate(command string) error {
+	return validatePlainIACCommands(string(OpentofuTool), command)
+}
+
+// WithOptions sets the OpenTofu options in the container, as environment variables.
+//
+// Parameters:
+// - inAutomation: Whether to adjust the output for automation (TF_IN_AUTOMATION). Optional parameter.
+// - disableInput: Whether to disable the prompts for the input variables (TF_INPUT). Optional parameter.
+// - dataDir: The directory where the working directory data is stored (TF_DATA_DIR). Optional parameter.
+// - workspace: The workspace to select (TF_WORKSPACE). Optional parameter.
+// - cliArgs: The extra arguments passed to every command (TF_CLI_ARGS). Optional parameter.
+// - pluginCacheDir: The directory where the providers are cached (TF_PLUGIN_CACHE_DIR). Optional parameter.
+// - cliConfigFile: The path to the CLI configuration file (TF_CLI_CONFIG_FILE). Optional parameter.
+// - encryption: The state and plan encryption configuration (TF_ENCRYPTION). Optional parameter.
+//
+// Returns:
+// - *OpentofuCmd: The updated command runner with the options set.
+func (c *OpentofuCmd) WithOptions(
+	// inAutomation is whether to adjust the output for automation.
+	// corresponds to the TF_IN_AUTOMATION environment variable.
+	// +optional
+	inAutomation bool,
+	// disableInput is whether to disable the prompts for the input variables.
+	// corresponds to the TF_INPUT environment variable.
+	// +optional
+	disableInput bool,
+	// dataDir is the directory where the working directory data is stored.
+	// corresponds to the TF_DATA_DIR environment variable.
+	// +optional
+	dataDir string,
+	// workspace is the workspace to select.
+	// corresponds to the TF_WORKSPACE environment variable.
+	// +optional
+	workspace string,
+	// cliArgs are the extra arguments passed to every command.
+	// corresponds to the TF_CLI_ARGS environment variable.
+	// +optional
+	cliArgs string,
+	// pluginCacheDir is the directory where the providers are cached.
+	// corresponds to the TF_PLUGIN_CACHE_DIR environment variable.
+	// +optional
+	pluginCacheDir string,
+	// cliConfigFile is the path to the CLI configuration file.
+	// corresponds to the TF_CLI_CONFIG_FILE environment variable.
+	// +optional
+	cliConfigFile string,
+	// encryption is the state and plan encryption configuration, in HCL or JSON. It's passed as a
+	// secret, since it usually includes the passphrase or the credentials of the key provider.
+	// corresponds to the TF_ENCRYPTION environment variable.
+	// +optional
+	encryption *dagger.Secret,
+) *OpentofuCmd {
+	tfOpts := newTfOptionsDagger(inAutomation, disableInput, dataDir, workspace, cliArgs, pluginCacheDir, cliConfigFile)
+
+	c.Tg.Ctr = tfOpts.WithTfOptionsSetInContainer(c.Tg.Ctr)
+
+	if encryption != nil {
+		c.Tg.registerSecretsToRedact([]*dagger.Secret{encryption})
+		c.Tg.Ctr = c.Tg.Ctr.
+			WithSecretVariable("TF_ENCRYPTION", encryption)
+	}
+
+	return c
+}
+
+// Exec executes a given opentofu command within a dagger container.
+// It returns the container with the command executed, or an error if the command is invalid.
+//
+//nolint:lll // It's okay, since the ignore pattern is included.
+func (c *OpentofuCmd) Exec(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// command is the opentofu command to execute. It's the actual command that comes after 'opentofu'
+	command string,
+	// args are the arguments to pass to the command.
+	// +optional
+	args []string,
+	// autoApprove is the flag to auto approve the command.
+	// +optional
+	autoApprove bool,
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute, relative to the source directory.
+	// +optional
+	module string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+) (*dagger.Container, error) {
+	return c.Tg.execIACCmd(ctx, c, command, args, autoApprove, source, module, envVars, secrets)
+}
+
+// ExecCmd executes a given opentofu command within a dagger container.
+// It returns the output of the command or an error if the command is invalid or fails to execute.
+// The values of the secrets, and the redaction patterns configured with WithRedaction, are redacted
+// from the output.
+//
+//nolint:lll // It's okay, since the ignore pattern is included.
+func (c *OpentofuCmd) ExecCmd(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// command is the opentofu command to execute. It's the actual command that comes after 'opentofu'
+	command string,
+	// args are the arguments to pass to the command.
+	// +optional
+	args []string,
+	// autoApprove is the flag to auto approve the command.
+	// +optional
+	autoApprove bool,
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute, relative to the source directory.
+	// +optional
+	module string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+) (string, error) {
+	return c.Tg.execIACCmdOutput(ctx, c, command, args, autoApprove, source, module, envVars, secrets)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
+)
+
+// TfOptsConfig holds the configuration and options for Terraform and OpenTofu. Both tools read
+// the same TF_* environment variables.
+type TfOptsConfig struct {
+	// TfOpts holds the Terraform and OpenTofu options.
+	// +private
+	TfOpts []TgConfigSetAsEnvVar
+}
+
+// newTfOptionsDagger creates a new TfOptsConfig with the provided parameters. Only the options
+// that are set are added.
+func newTfOptionsDagger(
+	// The flag to adjust the output for automation, e.g., without suggesting the next commands to run.
+	// Corresponds to the TF_IN_AUTOMATION environment variable.
+	inAutomation bool,
+	// The flag to disable the prompts for the input variables.
+	// Corresponds to the TF_INPUT environment variable.
+	disableInput bool,
+	// The directory where the working directory data (modules, providers, backend) is stored.
+	// Corresponds to the TF_DATA_DIR environment variable.
+	dataDir string,
+	// The workspace to select.
+	// Corresponds to the TF_WORKSPACE environment variable.
+	workspace string,
+	// The extra arguments passed to every command.
+	// Corresponds to the TF_CLI_ARGS environment variable.
+	cliArgs string,
+	// The directory where the providers are cached.
+	// Corresponds to the TF_PLUGIN_CACHE_DIR environment variable.
+	pluginCacheDir string,
+	// The path to the CLI configuration file.
+	// Corresponds to the TF_CLI_CONFIG_FILE environment variable.
+	cliConfigFile string,
+) *TfOptsConfig {
+	var daggers []TgConfigSetAsEnvVar
+
+	addStringFlag := func(key, value string) {
+		if strings.TrimSpace(value) != "" {
+			daggers = append(daggers, TgConfigSetAsEnvVar{
+				EnvVarKey:   key,
+				EnvVarValue: strings.TrimSpace(value),
+			})
+		}
+	}
+
+	if inAutomation {
+		addStringFlag("TF_IN_AUTOMATION", "true")
+	}
+
+	if disableInput {
+		addStringFlag("TF_INPUT", "0")
+	}
+
+	addStringFlag("TF_DATA_DIR", dataDir)
+	addStringFlag("TF_WORKSPACE", workspace)
+	addStringFlag("TF_CLI_ARGS", cliArgs)
+	addStringFlag("TF_PLUGIN_CACHE_DIR", pluginCacheDir)
+	addStringFlag("TF_CLI_CONFIG_FILE", cliConfigFile)
+
+	return &TfOptsConfig{TfOpts: daggers}
+}
+
+// withOption adds an option to the configuration, if its value is set.
+func (c *TfOptsConfig) withOption(key, value string) *TfOptsConfig {
+	if strings.TrimSpace(value) != "" {
+		c.TfOpts = append(c.TfOpts, TgConfigSetAsEnvVar{
+			EnvVarKey:   key,
+			EnvVarValue: strings.TrimSpace(value),
+		})
+	}
+
+	return c
+}
+
+// WithTfOptionsSetInContainer sets the environment variables of the options in the container.
+//
+// Parameters:
+// - ctr: A pointer to the dagger.Container in which the environment variables will be set.
+//
+// Returns:
+// - A pointer to the modified dagger.Container with the environment variables set.
+func (c *TfOptsConfig) WithTfOptionsSetInContainer(ctr *dagger.Container) *dagger.Container {
+	for _, envVar := range c.TfOpts {
+		ctr = ctr.
+			WithEnvVariable(envVar.EnvVarKey, envVar.EnvVarValue)
+	}
+
+	return ctr
+}
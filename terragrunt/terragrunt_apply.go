@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+
+	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
+)
+
+const (
+	// plansDir is the directory where the plan files are written or mounted in the container.
+	plansDir             = "/home/terragrunt/plans"
+	freshPlanFileName    = "fresh.tfplan"
+	planFileName         = "plan.tfplan"
+	reviewedPlanName     = "reviewed.tfplan"
+	applyLogFileName     = "apply.log"
+	outputsFileName      = "outputs.json"
+	planChecksumFileName = "plan-checksum"
+)
+
+// planChanges holds the parts of a JSON plan (the output of 'show -json') that describe
+// the changes to apply. Everything else (timestamps, versions, etc.) is ignored when
+// computing the checksum of a plan.
+type planChanges struct {
+	ResourceChanges interface{} `json:"resource_changes"`
+	OutputChanges   interface{} `json:"output_changes"`
+}
+
+// Plan runs 'plan' for the given unit, and returns the saved plan file.
+//
+// The returned plan file is meant to be reviewed, and then passed to Apply, which only
+// applies it if it still matches a freshly computed plan for the same source.
+//
+// Parameters:
+// - ctx: The context to use when executing the command.
+// - source: The source directory that includes the source code.
+// - module: The module or terragrunt unit to plan. Optional parameter.
+// - args: Extra arguments to pass to the plan command. Optional parameter.
+// - envVars: The environment variables to pass to the container. Optional parameter.
+// - secrets: The secrets to pass to the container. Optional parameter.
+//
+// Returns:
+// - *dagger.File: The saved plan file.
+// - error: An error if the plan command can't be prepared.
+//
+//nolint:lll // It's okay, since the ignore pattern is included.
+func (m *Terragrunt) Plan(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
+	// +optional
+	module string,
+	// args are extra arguments to pass to the plan command.
+	// +optional
+	args []string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+) (*dagger.File, error) {
+	planPath := filepath.Join(plansDir, planFileName)
+
+	planCmd, err := m.prepareExec(ctx, "plan", append(slices.Clone(args), "-out="+planPath), false,
+		source, module, envVars, secrets, "")
+	if err != nil {
+		return nil, WrapError(err, "failed to prepare the plan command")
+	}
+
+	return m.withPlansDir().
+		Ctr.
+		WithExec(planCmd).
+		File(planPath), nil
+}
+
+// PlanChecksum returns the checksum of the changes described by a saved plan file.
+//
+// The checksum is computed over the resource and output changes of the plan (as rendered by
+// 'show -json'), so two plans with the same changes have the same checksum, regardless of when
+// they were generated.
+//
+// Parameters:
+// - ctx: The context to use when executing the command.
+// - planFile: The saved plan file.
+// - source: The source directory that includes the source code.
+// - module: The module or terragrunt unit the plan belongs to. Optional parameter.
+// - envVars: The environment variables to pass to the container. Optional parameter.
+// - secrets: The secrets to pass to the container. Optional parameter.
+//
+// Returns:
+// - string: The checksum of the plan, as a hex-encoded SHA-256 hash.
+// - error: An error if the plan can't be rendered or parsed.
+//
+//nolint:lll // It's okay, since the ignore pattern is included.
+func (m *Terragrunt) PlanChecksum(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// planFile is the saved plan file.
+	planFile *dagger.File,
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
+	// +optional
+	module string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+) (string, error) {
+	reviewedPlanPath := filepath.Join(plansDir, reviewedPlanName)
+
+	showCmd, err := m.prepareExec(ctx, "show", []string{"-json", reviewedPlanPath}, false,
+		source, module, envVars, secrets, "")
+	if err != nil {
+		return "", WrapError(err, "failed to prepare the show command")
+	}
+
+	redactor, err := m.newRedactor(ctx)
+	if err != nil {
+		return "", WrapError(err, "failed to configure the redaction of the show output")
+	}
+
+	ctr := m.withPlansDir().
+		Ctr.
+		WithMountedFile(reviewedPlanPath, planFile, dagger.ContainerWithMountedFileOpts{
+			Owner: terragruntCtrUser,
+		})
+
+	return planChecksumFromCtr(ctx, ctr, showCmd, redactor)
+}
+
+// Apply applies a reviewed plan file, only if it matches a freshly computed plan for the same source.
+//
+// The workflow is the following:
+// 1. A fresh plan is computed for the given source and unit.
+// 2. The checksums of the reviewed plan and the fresh plan are compared. If they differ, or if an
+// approved checksum is passed and it doesn't match the reviewed plan, the apply is refused.
+// 3. The reviewed plan file is applied as is, and the outputs are read.
+//
+// The returned directory contains:
+// - apply.log: The output of the apply command, with the sensitive values redacted.
+// - outputs.json: The outputs of the unit after the apply, in JSON format, with the values of the
+// sensitive outputs masked and the sensitive values redacted.
+// - plan-checksum: The checksum of the applied plan.
+//
+// Parameters:
+// - ctx: The context to use when executing the command.
+// - planFile: The reviewed plan file to apply.
+// - source: The source directory that includes the source code.
+// - module: The module or terragrunt unit to apply. Optional parameter.
+// - approvedChecksum: The checksum of the plan that was approved. Optional parameter.
+// - planArgs: Extra arguments to pass to the fresh plan command. Optional parameter.
+// - envVars: The environment variables to pass to the container. Optional parameter.
+// - secrets: The secrets to pass to the container. Optional parameter.
+//
+// Returns:
+// - *dagger.Directory: The directory with the apply log, the outputs and the plan checksum.
+// - error: An error if the plan doesn't match, or if any of the commands fail.
+//
+//nolint:funlen,lll // It's okay, the workflow is sequential and easier to follow in a single function.
+func (m *Terragrunt) Apply(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// planFile is the reviewed plan file to apply.
+	planFile *dagger.File,
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
+	// +optional
+	module string,
+	// approvedChecksum is the checksum of the plan that was approved, as returned by PlanChecksum.
+	// +optional
+	approvedChecksum string,
+	// planArgs are extra arguments to pass to the fresh plan command. They should match the ones used
+	// to generate the reviewed plan.
+	// +optional
+	planArgs []string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+) (*dagger.Directory, error) {
+	if planFile == nil {
+		return nil, Errorf("a reviewed plan file is required, can't apply without it")
+	}
+
+	freshPlanPath := filepath.Join(plansDir, freshPlanFileName)
+	reviewedPlanPath := filepath.Join(plansDir, reviewedPlanName)
+
+	freshPlanCmd, err := m.prepareExec(ctx, "plan", append(slices.Clone(planArgs), "-out="+freshPlanPath), false,
+		source, module, envVars, secrets, "")
+	if err != nil {
+		return nil, WrapError(err, "failed to prepare the plan command")
+	}
+
+	entrypoint := m.Tg.getEntrypoint()
+
+	redactor, err := m.newRedactor(ctx)
+	if err != nil {
+		return nil, WrapError(err, "failed to configure the redaction of the apply log")
+	}
+
+	freshPlan, err := runRedactedExec(ctx, m.withPlansDir().
+		Ctr.
+		WithMountedFile(reviewedPlanPath, planFile, dagger.ContainerWithMountedFileOpts{
+			Owner: terragruntCtrUser,
+		}), freshPlanCmd)
+	if err != nil {
+		return nil, WrapError(err, "failed to compute the fresh plan")
+	}
+
+	if err := freshPlan.failure(redactor, "plan"); err != nil {
+		return nil, WrapError(err, "failed to compute the fresh plan")
+	}
+
+	ctr := freshPlan.ctr
+
+	freshChecksum, err := planChecksumFromCtr(ctx, ctr, []string{entrypoint, "show", "-json", freshPlanPath}, redactor)
+	if err != nil {
+		return nil, WrapError(err, "failed to compute the checksum of the fresh plan")
+	}
+
+	reviewedChecksum, err := planChecksumFromCtr(ctx, ctr, []string{entrypoint, "show", "-json", reviewedPlanPath},
+		redactor)
+	if err != nil {
+		return nil, WrapError(err, "failed to compute the checksum of the reviewed plan")
+	}
+
+	if reviewedChecksum != freshChecksum {
+		return nil, Errorf("the reviewed plan (checksum %s) doesn't match the fresh plan (checksum %s), refusing to apply",
+			reviewedChecksum, freshChecksum)
+	}
+
+	if approvedChecksum != "" && approvedChecksum != reviewedChecksum {
+		return nil, Errorf("the reviewed plan (checksum %s) doesn't match the approved checksum %s, refusing to apply",
+			reviewedChecksum, approvedChecksum)
+	}
+
+	applied, err := runRedactedExec(ctx, ctr, []string{entrypoint, "apply", reviewedPlanPath})
+	if err != nil {
+		return nil, WrapError(err, "failed to apply the reviewed plan")
+	}
+
+	if err := applied.failure(redactor, "apply"); err != nil {
+		return nil, WrapError(err, "failed to apply the reviewed plan")
+	}
+
+	outputs, err := runRedactedExec(ctx, applied.ctr, []string{entrypoint, "output", "-json"})
+	if err != nil {
+		return nil, WrapError(err, "failed to get the outputs after the apply")
+	}
+
+	if err := outputs.failure(redactor, "output"); err != nil {
+		return nil, WrapError(err, "failed to get the outputs after the apply")
+	}
+
+	redactedOutputs, err := redactOutputs(outputs.stdout, redactor)
+	if err != nil {
+		return nil, WrapError(err, "failed to redact the outputs after the apply")
+	}
+
+	return dag.
+		Directory().
+		WithNewFile(applyLogFileName, redactor.redact(applied.stdout)).
+		WithNewFile(outputsFileName, redactedOutputs).
+		WithNewFile(planChecksumFileName, reviewedChecksum), nil
+}
+
+// withPlansDir creates the plans directory in the container, owned by the terragrunt user,
+// and forwards the terraform stdout so the JSON output of 'show' can be parsed.
+func (m *Terragrunt) withPlansDir() *Terragrunt {
+	m.Ctr = m.Ctr.
+		WithDirectory(plansDir, dag.Directory(), dagger.ContainerWithDirectoryOpts{
+			Owner: terragruntCtrUser,
+		}).
+		WithEnvVariable("TERRAGRUNT_FORWARD_TF_STDOUT", "true")
+
+	return m
+}
+
+// planChecksumFromCtr runs the given 'show -json' command in the container, and returns the
+// checksum of the plan changes. If the command fails, its stderr is redacted from the error.
+func planChecksumFromCtr(ctx context.Context, ctr *dagger.Container, showCmd []string, r *redactor) (string, error) {
+	show, err := runRedactedExec(ctx, ctr, showCmd)
+	if err != nil {
+		return "", WrapError(err, "failed to render the plan as JSON")
+	}
+
+	if err := show.failure(r, "show"); err != nil {
+		return "", WrapError(err, "failed to render the plan as JSON")
+	}
+
+	return computePlanChecksum(show.stdout)
+}
+
+// redactOutputs masks the values of the outputs marked as sensitive in the JSON outputs of
+// 'output -json', and redacts the secret values and the patterns from the rest.
+func redactOutputs(outputsAsJSON string, r *redactor) (string, error) {
+	var outputs map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(outputsAsJSON), &outputs); err != nil {
+		return "", WrapError(err, "failed to parse the outputs as JSON")
+	}
+
+	for _, output := range outputs {
+		if sensitive, _ := output["sensitive"].(bool); sensitive {
+			output["value"] = redactedMask
+		}
+	}
+
+	masked, err := json.MarshalIndent(outputs, "", "  ")
+	if err != nil {
+		return "", WrapError(err, "failed to encode the outputs as JSON")
+	}
+
+	return r.redact(string(masked)), nil
+}
+
+// computePlanChecksum returns the hex-encoded SHA-256 hash of the resource and output changes
+// of a JSON plan. The changes are re-encoded before hashing, so the key order doesn't matter.
+func computePlanChecksum(planAsJSON string) (string, error) {
+	var changes planChanges
+	if err := json.Unmarshal([]byte(planAsJSON), &changes); err != nil {
+		return "", WrapError(err, "failed to parse the plan as JSON")
+	}
+
+	normalized, err := json.Marshal(changes)
+	if err != nil {
+		return "", WrapError(err, "failed to normalize the plan changes")
+	}
+
+	checksum := sha256.Sum256(normalized)
+
+	return hex.EncodeToString(checksum[:]), nil
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
+	"github.com/Excoriate/daggerx/pkg/fixtures"
+)
+
+const (
+	// renderedJSONFileName is the name of the file written by 'render-json' in each unit.
+	renderedJSONFileName  = "terragrunt_rendered.json"
+	auditJSONFileName     = "module-pinning-audit.json"
+	auditTableFileName    = "module-pinning-audit.txt"
+	auditFindingUnpinned  = "unpinned"
+	auditFindingBranchRef = "branch-ref"
+	auditFindingMismatch  = "inconsistent-versions"
+)
+
+var (
+	// semverRefRegex matches refs that look like a release tag, e.g., "v1.2.3", "1.2", "v2.0.0-rc.1".
+	semverRefRegex = regexp.MustCompile(`^v?\d+(\.\d+){0,2}([-+][0-9A-Za-z.\-+]+)?$`)
+	// commitRefRegex matches refs that look like a (short or full) commit SHA.
+	commitRefRegex = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+)
+
+// ModuleSourceRef represents the terraform.source reference of a Terragrunt unit.
+type ModuleSourceRef struct {
+	// Unit is the path of the unit, relative to the source directory.
+	Unit string
+	// Source is the terraform.source value, as rendered by Terragrunt.
+	Source string
+	// Module is the source without the ref or version, used to compare units using the same module.
+	Module string
+	// Ref is the git ref or the registry version the source is pinned to. It's empty if unpinned.
+	Ref string
+}
+
+// ModulePinningFinding represents an issue found when auditing the module versions of a stack.
+type ModulePinningFinding struct {
+	// Kind is the kind of finding: "unpinned", "branch-ref" or "inconsistent-versions".
+	Kind string
+	// Module is the module the finding is about.
+	Module string
+	// Units are the units affected by the finding.
+	Units []string
+	// Detail is a human-readable description of the finding.
+	Detail string
+}
+
+// ModulePinningAudit holds the result of a module version pinning audit.
+type ModulePinningAudit struct {
+	// Refs are all the remote module source references found in the stack.
+	Refs []ModuleSourceRef
+	// Findings are the issues found in the stack.
+	Findings []ModulePinningFinding
+}
+
+// renderedTerragruntConfig holds the parts of a 'render-json' output used by the audit.
+type renderedTerragruntConfig struct {
+	Terraform *struct {
+		Source string `json:"source"`
+	} `json:"terraform"`
+}
+
+// AuditModuleVersions audits the module versions used by every unit in the source directory.
+//
+// Every terragrunt.hcl file is rendered with 'render-json', so sources built from locals or
+// includes are resolved, and the terraform.source references are extracted. The audit reports:
+// - unpinned: remote sources without a ref or version.
+// - branch-ref: sources pinned to a ref that doesn't look like a tag or a commit (e.g., "main").
+// - inconsistent-versions: the same module used with different refs across units.
+//
+// The returned directory contains the report in JSON (module-pinning-audit.json) and as a
+// table (module-pinning-audit.txt).
+//
+// Parameters:
+// - ctx: The context to use when executing the command.
+// - source: The source directory that includes the Terragrunt units.
+// - envVars: The environment variables to pass to the container. Optional parameter.
+// - secrets: The secrets to pass to the container. Optional parameter.
+// - failOnFindings: Whether to return an error if any finding is reported. Optional parameter.
+//
+// Returns:
+// - *dagger.Directory: The directory with the audit report.
+// - error: An error if no unit can be rendered, or if there are findings and failOnFindings is set.
+//
+//nolint:lll // It's okay, since the ignore pattern is included.
+func (m *Terragrunt) AuditModuleVersions(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// source is the source directory that includes the terragrunt units.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+	// failOnFindings is whether to return an error if any finding is reported.
+	// +optional
+	failOnFindings bool,
+) (*dagger.Directory, error) {
+	renderCmd, err := m.prepareExec(ctx, "run-all", []string{"render-json"}, false, source, "", envVars, secrets, "")
+	if err != nil {
+		return nil, WrapError(err, "failed to prepare the render-json command")
+	}
+
+	// Units that can't be rendered (e.g., dependencies without mock outputs) are skipped, so the
+	// rest of the stack is still audited.
+	renderedSrc := m.Ctr.
+		WithEnvVariable("TERRAGRUNT_NON_INTERACTIVE", "true").
+		WithExec(renderCmd, dagger.ContainerWithExecOpts{
+			Expect: dagger.ReturnTypeAny,
+		}).
+		Directory(fixtures.MntPrefix)
+
+	renderedFiles, err := renderedSrc.Glob(ctx, "**/"+renderedJSONFileName)
+	if err != nil {
+		return nil, WrapError(err, "failed to find the rendered terragrunt configurations")
+	}
+
+	if len(renderedFiles) == 0 {
+		return nil, Errorf("no terragrunt configuration could be rendered in the source directory")
+	}
+
+	var refs []ModuleSourceRef
+
+	for _, renderedFile := range renderedFiles {
+		if strings.Contains(renderedFile, ".terragrunt-cache") {
+			continue
+		}
+
+		content, err := renderedSrc.File(renderedFile).Contents(ctx)
+		if err != nil {
+			return nil, WrapErrorf(err, "failed to read the rendered configuration: %s", renderedFile)
+		}
+
+		ref, isRemote, err := parseRenderedModuleSource(filepath.Dir(renderedFile), content)
+		if err != nil {
+			return nil, WrapErrorf(err, "failed to parse the rendered configuration: %s", renderedFile)
+		}
+
+		if isRemote {
+			refs = append(refs, ref)
+		}
+	}
+
+	audit := auditModuleSourceRefs(refs)
+
+	auditAsJSON, err := json.MarshalIndent(audit, "", "  ")
+	if err != nil {
+		return nil, WrapError(err, "failed to marshal the audit report")
+	}
+
+	if failOnFindings && len(audit.Findings) > 0 {
+		return nil, Errorf("the module pinning audit reported %d finding(s):\n%s",
+			len(audit.Findings), formatModulePinningAuditTable(audit))
+	}
+
+	return dag.
+		Directory().
+		WithNewFile(auditJSONFileName, string(auditAsJSON)).
+		WithNewFile(auditTableFileName, formatModulePinningAuditTable(audit)), nil
+}
+
+// parseRenderedModuleSource extracts the terraform.source reference of a unit from its rendered
+// configuration. It returns false if the unit has no source, or if the source is a local path.
+func parseRenderedModuleSource(unit, renderedConfig string) (ModuleSourceRef, bool, error) {
+	var cfg renderedTerragruntConfig
+	if err := json.Unmarshal([]byte(renderedConfig), &cfg); err != nil {
+		return ModuleSourceRef{}, false, WrapError(err, "failed to unmarshal the rendered configuration")
+	}
+
+	if cfg.Terraform == nil || !isRemoteModuleSource(cfg.Terraform.Source) {
+		return ModuleSourceRef{}, false, nil
+	}
+
+	module, ref := splitModuleSourceRef(cfg.Terraform.Source)
+
+	return ModuleSourceRef{
+		Unit:   unit,
+		Source: cfg.Terraform.Source,
+		Module: module,
+		Ref:    ref,
+	}, true, nil
+}
+
+// isRemoteModuleSource returns true if the source is fetched from a remote location (git, registry,
+// http, buckets). Local paths, with or without a leading "./", return false.
+func isRemoteModuleSource(source string) bool {
+	if source == "" {
+		return false
+	}
+
+	if strings.Contains(source, "::") || strings.Contains(source, "://") {
+		return true
+	}
+
+	for _, prefix := range []string{"github.com/", "gitlab.com/", "bitbucket.org/", "git@"} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitModuleSourceRef splits a module source into the module (without the ref or version
+// query parameters) and the ref or version it's pinned to.
+func splitModuleSourceRef(source string) (string, string) {
+	module, rawQuery, found := strings.Cut(source, "?")
+	if !found {
+		return source, ""
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return module, ""
+	}
+
+	if ref := query.Get("ref"); ref != "" {
+		return module, ref
+	}
+
+	return module, query.Get("version")
+}
+
+// isBranchLikeRef returns true if the ref doesn't look like a release tag or a commit SHA.
+func isBranchLikeRef(ref string) bool {
+	return !semverRefRegex.MatchString(ref) && !commitRefRegex.MatchString(ref)
+}
+
+// auditModuleSourceRefs computes the findings for the given module source references.
+func auditModuleSourceRefs(refs []ModuleSourceRef) ModulePinningAudit {
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Unit < refs[j].Unit
+	})
+
+	audit := ModulePinningAudit{
+		Refs:     refs,
+		Findings: []ModulePinningFinding{},
+	}
+
+	unitsByModuleRef := map[string]map[string][]string{}
+
+	for _, ref := range refs {
+		switch {
+		case ref.Ref == "":
+			audit.Findings = append(audit.Findings, ModulePinningFinding{
+				Kind:   auditFindingUnpinned,
+				Module: ref.Module,
+				Units:  []string{ref.Unit},
+				Detail: "the source isn't pinned to any ref or version",
+			})
+		case isBranchLikeRef(ref.Ref):
+			audit.Findings = append(audit.Findings, ModulePinningFinding{
+				Kind:   auditFindingBranchRef,
+				Module: ref.Module,
+				Units:  []string{ref.Unit},
+				Detail: fmt.Sprintf("the source is pinned to %q, which looks like a branch", ref.Ref),
+			})
+		}
+
+		if _, ok := unitsByModuleRef[ref.Module]; !ok {
+			unitsByModuleRef[ref.Module] = map[string][]string{}
+		}
+
+		unitsByModuleRef[ref.Module][ref.Ref] = append(unitsByModuleRef[ref.Module][ref.Ref], ref.Unit)
+	}
+
+	modules := make([]string, 0, len(unitsByModuleRef))
+	for module := range unitsByModuleRef {
+		modules = append(modules, module)
+	}
+
+	sort.Strings(modules)
+
+	for _, module := range modules {
+		unitsByRef := unitsByModuleRef[module]
+		if len(unitsByRef) < 2 { //nolint:mnd // More than one ref for the same module is inconsistent.
+			continue
+		}
+
+		var (
+			versions []string
+			units    []string
+		)
+
+		for ref, refUnits := range unitsByRef {
+			if ref == "" {
+				ref = "<unpinned>"
+			}
+
+			versions = append(versions, ref)
+			units = append(units, refUnits...)
+		}
+
+		sort.Strings(versions)
+		sort.Strings(units)
+
+		audit.Findings = append(audit.Findings, ModulePinningFinding{
+			Kind:   auditFindingMismatch,
+			Module: module,
+			Units:  units,
+			Detail: "the module is used with different versions: " + strings.Join(versions, ", "),
+		})
+	}
+
+	return audit
+}
+
+// formatModulePinningAuditTable renders the audit as a table, with the references first and the findings after.
+func formatModulePinningAuditTable(audit ModulePinningAudit) string {
+	var sb strings.Builder
+
+	writer := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0) //nolint:mnd // Padding of the table columns.
+
+	_, _ = fmt.Fprintln(writer, "UNIT\tMODULE\tREF")
+
+	for _, ref := range audit.Refs {
+		pinnedRef := ref.Ref
+		if pinnedRef == "" {
+			pinnedRef = "-"
+		}
+
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\n", ref.Unit, ref.Module, pinnedRef)
+	}
+
+	_, _ = fmt.Fprintln(writer)
+	_, _ = fmt.Fprintln(writer, "FINDING\tMODULE\tUNITS\tDETAIL")
+
+	for _, finding := range audit.Findings {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n",
+			finding.Kind, finding.Module, strings.Join(finding.Units, ","), finding.Detail)
+	}
+
+	_ = writer.Flush()
+
+	return sb.String()
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
 
 	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
 	"github.com/Excoriate/daggerx/pkg/cmdx"
@@ -51,7 +54,7 @@ func (c *TerragruntCmd) validate(command string) error {
 // Exec executes a given command within a dagger container.
 // It returns the output of the command or an error if the command is invalid or fails to execute.
 //
-//nolint:lll,cyclop // It's okay, since the ignore pattern is included
+//nolint:lll // It's okay, since the ignore pattern is included
 func (m *Terragrunt) Exec(
 	// ctx is the context to use when executing the command.
 	// +optional
@@ -82,12 +85,269 @@ func (m *Terragrunt) Exec(
 	// +optional
 	tool string,
 ) (*dagger.Container, error) {
+	cmd, err := m.prepareExec(ctx, command, args, autoApprove, source, module, envVars, secrets, tool)
+	if err != nil {
+		return nil, err
+	}
+
+	// Execute the command
+	return m.Ctr.
+		WithExec(cmd), nil
+}
+
+// ExecCmd executes a given command within a dagger container.
+// It returns the output of the command or an error if the command is invalid or fails to execute.
+// The values of the secrets, and the redaction patterns configured with WithRedaction, are redacted
+// from the output. If the command fails, the error only holds its exit code and its redacted stderr.
+//
+//nolint:lll // It's okay, since the ignore pattern is included.
+func (m *Terragrunt) ExecCmd(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// command is the terragrunt command to execute. It's the actual command that comes after 'terragrunt'
+	command string,
+	// args are the arguments to pass to the command.
+	// +optional
+	args []string,
+	// autoApprove is the flag to auto approve the command.
+	// +optional
+	autoApprove bool,
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
+	// +optional
+	module string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+	// tool is the tool to use for executing the command.
+	// +optional
+	tool string,
+) (string, error) {
+	cmd, err := m.prepareExec(ctx, command, args, autoApprove, source, module, envVars, secrets, tool)
+	if err != nil {
+		return "", WrapErrorf(err, "failed to prepare terragrunt command: %s", command)
+	}
+
+	redactor, err := m.newRedactor(ctx)
+	if err != nil {
+		return "", WrapError(err, "failed to configure the redaction of the output")
+	}
+
+	result, err := runRedactedExec(ctx, m.Ctr, cmd)
+	if err != nil {
+		return "", WrapErrorf(err, "failed to execute terragrunt command: %s", command)
+	}
+
+	if err := result.failure(redactor, command); err != nil {
+		return "", err
+	}
+
+	return redactor.redact(result.stdout), nil
+}
+
+// ExecWithLogs executes a given command within a dagger container, and returns its logs as a directory.
+//
+// The Terraform logs are written to a dedicated file through TF_LOG_PATH, and the Terragrunt logs
+// (written to stderr) are captured along with the stdout and the exit code of the command. The logs
+// are returned even when the command fails. If the Terraform log level is JSON, the Terraform logs
+// are also parsed into structured entries.
+//
+// The returned directory contains:
+// - terraform.log: The Terraform logs.
+// - terragrunt.log: The Terragrunt logs (stderr).
+// - stdout.log: The stdout of the command.
+// - exit-code: The exit code of the command.
+// - terraform-log.json: The parsed Terraform logs, only if the Terraform log level is JSON.
+//
+// The values of the secrets, and the redaction patterns configured with WithRedaction, are redacted
+// from all the logs.
+//
+//nolint:lll,funlen // It's okay, since the ignore pattern is included.
+func (m *Terragrunt) ExecWithLogs(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// command is the terragrunt command to execute. It's the actual command that comes after 'terragrunt'
+	command string,
+	// args are the arguments to pass to the command.
+	// +optional
+	args []string,
+	// autoApprove is the flag to auto approve the command.
+	// +optional
+	autoApprove bool,
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
+	// +optional
+	module string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+	// tool is the tool to use for executing the command.
+	// +optional
+	tool string,
+	// tfLog is the terraform log level to use. If not set, the TF_LOG value set in the container is used,
+	// or INFO if it's not set either. Use JSON to get the logs parsed into structured entries.
+	// +optional
+	tfLog string,
+) (*dagger.Directory, error) {
+	cmd, err := m.prepareExec(ctx, command, args, autoApprove, source, module, envVars, secrets, tool)
+	if err != nil {
+		return nil, err
+	}
+
+	tfLogLevel, err := m.resolveTerraformLogLevel(ctx, tfLog)
+	if err != nil {
+		return nil, WrapError(err, "failed to resolve the terraform log level")
+	}
+
+	tfLogPath := filepath.Join(execLogsDir, tfLogFileName)
+
+	ctr := m.Ctr.
+		WithDirectory(execLogsDir, dag.Directory(), dagger.ContainerWithDirectoryOpts{
+			Owner: terragruntCtrUser,
+		}).
+		WithNewFile(tfLogPath, "", dagger.ContainerWithNewFileOpts{
+			Owner: terragruntCtrUser,
+		}).
+		WithEnvVariable("TF_LOG", tfLogLevel).
+		WithEnvVariable("TF_LOG_PATH", tfLogPath).
+		WithExec(cmd, dagger.ContainerWithExecOpts{
+			Expect: dagger.ReturnTypeAny,
+		})
+
+	stdout, err := ctr.Stdout(ctx)
+	if err != nil {
+		return nil, WrapErrorf(err, "failed to get stdout from command: %s", command)
+	}
+
+	stderr, err := ctr.Stderr(ctx)
+	if err != nil {
+		return nil, WrapErrorf(err, "failed to get stderr from command: %s", command)
+	}
+
+	exitCode, err := ctr.ExitCode(ctx)
+	if err != nil {
+		return nil, WrapErrorf(err, "failed to get the exit code from command: %s", command)
+	}
+
+	redactor, err := m.newRedactor(ctx)
+	if err != nil {
+		return nil, WrapError(err, "failed to configure the redaction of the logs")
+	}
+
+	tfLogFile, err := redactor.redactFile(ctx, tfLogFileName, ctr.File(tfLogPath))
+	if err != nil {
+		return nil, WrapError(err, "failed to redact the terraform logs")
+	}
+
+	logsDir := dag.
+		Directory().
+		WithFile(tfLogFileName, tfLogFile).
+		WithNewFile(tgLogFileName, redactor.redact(stderr)).
+		WithNewFile(stdoutLogFileName, redactor.redact(stdout)).
+		WithNewFile(exitCodeFileName, strconv.Itoa(exitCode))
+
+	if tfLogLevel != tfLogLevelJSON {
+		return logsDir, nil
+	}
+
+	entries, err := m.ParseTerraformJSONLogs(ctx, tfLogFile)
+	if err != nil {
+		return nil, WrapError(err, "failed to parse the terraform JSON logs")
+	}
+
+	entriesAsJSON, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return nil, WrapError(err, "failed to marshal the terraform log entries")
+	}
+
+	return logsDir.
+		WithNewFile(tfJSONLogFileName, string(entriesAsJSON)), nil
+}
+
+// prepareExec validates the command, and prepares the container to execute it.
+// It mounts the source directory, sets the environment variables and the secrets, and
+// returns the command (including the entrypoint) to execute.
+//
+// If a tool is passed, the command is validated and executed by the implementation of that tool,
+// otherwise it's validated as a Terragrunt command.
+//
+//nolint:lll // It's okay, since the ignore pattern is included
+func (m *Terragrunt) prepareExec(
+	ctx context.Context,
+	command string,
+	args []string,
+	autoApprove bool,
+	source *dagger.Directory,
+	module string,
+	envVars []string,
+	secrets []*dagger.Secret,
+	tool string,
+) ([]string, error) {
+	iacCmd, err := m.getIACCmd(tool)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.prepareCmdExec(ctx, iacCmd, command, args, autoApprove, source, module, envVars, secrets)
+}
+
+// getIACCmd returns the command implementation of the given tool. If no tool is passed, the
+// Terragrunt implementation is returned.
+func (m *Terragrunt) getIACCmd(tool string) (Cmd, error) {
+	if tool == "" {
+		return m.Tg, nil
+	}
+
+	if err := IsValidIACTool(tool); err != nil {
+		return nil, WrapErrorf(err, "failed to set the entrypoint with tool: %s", tool)
+	}
+
+	switch Tool(tool) {
+	case TerraformTool:
+		return m.Terraform(), nil
+	case OpentofuTool:
+		return m.Opentofu(), nil
+	default:
+		return m.Tg, nil
+	}
+}
+
+// prepareCmdExec validates the command with the given IaC command implementation, and prepares the
+// container to execute it. It mounts the source directory, sets the environment variables and the
+// secrets, and returns the command (including the entrypoint of the implementation) to execute.
+//
+//nolint:lll,cyclop // It's okay, since the ignore pattern is included
+func (m *Terragrunt) prepareCmdExec(
+	ctx context.Context,
+	iacCmd Cmd,
+	command string,
+	args []string,
+	autoApprove bool,
+	source *dagger.Directory,
+	module string,
+	envVars []string,
+	secrets []*dagger.Secret,
+) ([]string, error) {
 	// No too sure about this, but it's a good practice to have a context.
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	if err := m.Tg.validate(command); err != nil {
+	if err := iacCmd.validate(command); err != nil {
 		return nil, WrapErrorf(err, "failed to validate command: %s", command)
 	}
 
@@ -129,74 +389,68 @@ func (m *Terragrunt) Exec(
 	}
 
 	// if secrets are passed.
-	for _, secret := range secrets {
-		secretName, _ := secret.Name(ctx)
-		m.Ctr = m.
-			Ctr.
-			WithSecretVariable(secretName, secret)
+	if _, err := m.WithSecretsAsEnvVars(ctx, secrets); err != nil {
+		return nil, WrapError(err, "failed to set the secrets as environment variables")
 	}
 
-	// Set the entrypoint
-	if tool != "" {
-		if err := IsValidIACTool(tool); err != nil {
-			return nil, WrapErrorf(err, "failed to set the entrypoint with tool: %s", tool)
-		}
+	return append([]string{iacCmd.getEntrypoint()}, cmdAsSlice...), nil
+}
 
-		return m.Ctr.WithExec(append([]string{tool}, cmdAsSlice...)), nil
+// execIACCmd prepares the command with the given IaC command implementation, and executes it in the container.
+func (m *Terragrunt) execIACCmd(
+	ctx context.Context,
+	iacCmd Cmd,
+	command string,
+	args []string,
+	autoApprove bool,
+	source *dagger.Directory,
+	module string,
+	envVars []string,
+	secrets []*dagger.Secret,
+) (*dagger.Container, error) {
+	cmd, err := m.prepareCmdExec(ctx, iacCmd, command, args, autoApprove, source, module, envVars, secrets)
+	if err != nil {
+		return nil, err
 	}
 
-	// Execute the command
 	return m.Ctr.
-		WithExec(append([]string{m.Tg.getEntrypoint()}, cmdAsSlice...)), nil
+		WithExec(cmd), nil
 }
 
-// ExecCmd executes a given command within a dagger container.
-// It returns the output of the command or an error if the command is invalid or fails to execute.
-//
-//nolint:lll,contextcheck // It's okay, since the ignore pattern is included.
-func (m *Terragrunt) ExecCmd(
-	// ctx is the context to use when executing the command.
-	// +optional
+// execIACCmdOutput executes the command with the given IaC command implementation, and returns its
+// stdout, with the values of the secrets and the redaction patterns redacted. If the command fails,
+// the error only holds its exit code and its redacted stderr.
+func (m *Terragrunt) execIACCmdOutput(
 	ctx context.Context,
-	// command is the terragrunt command to execute. It's the actual command that comes after 'terragrunt'
+	iacCmd Cmd,
 	command string,
-	// args are the arguments to pass to the command.
-	// +optional
 	args []string,
-	// autoApprove is the flag to auto approve the command.
-	// +optional
 	autoApprove bool,
-	// source is the source directory that includes the source code.
-	// +defaultPath="/"
-	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
 	source *dagger.Directory,
-	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
-	// +optional
 	module string,
-	// envVars is the environment variables to pass to the container.
-	// +optional
 	envVars []string,
-	// secrets is the secrets to pass to the container.
-	// +optional
 	secrets []*dagger.Secret,
-	// tool is the tool to use for executing the command.
-	// +optional
-	tool string,
 ) (string, error) {
-	container, err := m.Exec(ctx, command, args, autoApprove, source, module, envVars, secrets, tool)
-
+	cmd, err := m.prepareCmdExec(ctx, iacCmd, command, args, autoApprove, source, module, envVars, secrets)
 	if err != nil {
-		return "", WrapErrorf(err, "failed to execute terragrunt command: %s", command)
+		return "", WrapErrorf(err, "failed to prepare %s command: %s", iacCmd.getEntrypoint(), command)
 	}
 
-	output, err := container.
-		Stdout(context.Background())
+	redactor, err := m.newRedactor(ctx)
+	if err != nil {
+		return "", WrapError(err, "failed to configure the redaction of the output")
+	}
 
+	result, err := runRedactedExec(ctx, m.Ctr, cmd)
 	if err != nil {
-		return "", WrapErrorf(err, "failed to get stdout from terragrunt command: %s", command)
+		return "", WrapErrorf(err, "failed to execute %s command: %s", iacCmd.getEntrypoint(), command)
 	}
 
-	return output, nil
+	if err := result.failure(redactor, command); err != nil {
+		return "", err
+	}
+
+	return redactor.redact(result.stdout), nil
 }
 
 // GetTerragruntCacheDir returns the terragrunt cache directory.
@@ -558,6 +812,8 @@ func (m *Terragrunt) WithTerraformToken(
 		Ctr.
 		WithSecretVariable(tfTokenName, tfToken)
 
+	m.registerSecretsToRedact([]*dagger.Secret{tfToken})
+
 	return m, nil
 }
 
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+
+	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
+)
+
+const (
+	awsSharedCredentialsFile = "/home/terragrunt/.aws/credentials"
+	awsConfigFile            = "/home/terragrunt/.aws/config"
+	awsWebIdentityTokenFile  = "/home/terragrunt/.aws/web-identity-token"
+	gcpCredentialsFile       = "/home/terragrunt/.config/gcloud/application_default_credentials.json"
+	azureCertificateFile     = "/home/terragrunt/.azure/client-certificate.pfx"
+	azureOIDCTokenFile       = "/home/terragrunt/.azure/oidc-token"
+	// credentialsFileMode is the mode of the credential files mounted as secrets (read-only for the owner).
+	credentialsFileMode = 0o400
+)
+
+// WithAWSSharedCredentials mounts an AWS shared credentials file and/or config file in the container.
+//
+// Both files are mounted as Dagger secrets, so their content never ends up in environment
+// variables or logs. Only their paths are set in the AWS_SHARED_CREDENTIALS_FILE and
+// AWS_CONFIG_FILE environment variables. The profile, if passed, is set in AWS_PROFILE.
+//
+// Parameters:
+// - credentialsFile: The AWS shared credentials file. Optional parameter.
+// - configFile: The AWS config file, e.g., with named profiles or SSO settings. Optional parameter.
+// - profile: The named profile to use. Optional parameter.
+//
+// Returns:
+// - *Terragrunt: The updated Terragrunt instance with the AWS shared files mounted.
+// - error: An error if neither the credentials file nor the config file are passed.
+func (m *Terragrunt) WithAWSSharedCredentials(
+	// credentialsFile is the AWS shared credentials file.
+	// +optional
+	credentialsFile *dagger.Secret,
+	// configFile is the AWS config file.
+	// +optional
+	configFile *dagger.Secret,
+	// profile is the named profile to use. It's set in the AWS_PROFILE environment variable.
+	// +optional
+	profile string,
+) (*Terragrunt, error) {
+	if credentialsFile == nil && configFile == nil {
+		return nil, Errorf("either the AWS credentials file or the AWS config file is required")
+	}
+
+	if credentialsFile != nil {
+		m.withCredentialsFile(awsSharedCredentialsFile, credentialsFile, "AWS_SHARED_CREDENTIALS_FILE")
+	}
+
+	if configFile != nil {
+		m.withCredentialsFile(awsConfigFile, configFile, "AWS_CONFIG_FILE")
+	}
+
+	if profile != "" {
+		m.Ctr = m.Ctr.
+			WithEnvVariable("AWS_PROFILE", profile)
+	}
+
+	return m, nil
+}
+
+// WithAWSWebIdentity configures AWS credentials from a web identity (OIDC) token file.
+//
+// The token is mounted as a Dagger secret, and its path is set in the AWS_WEB_IDENTITY_TOKEN_FILE
+// environment variable, along with AWS_ROLE_ARN and AWS_ROLE_SESSION_NAME. The AWS SDKs used by
+// Terraform, OpenTofu and Terragrunt exchange the token for temporary credentials.
+//
+// Parameters:
+// - token: The web identity token, e.g., the OIDC token issued by the CI provider.
+// - roleArn: The ARN of the role to assume with the web identity token.
+// - sessionName: The name of the role session. Optional parameter.
+//
+// Returns:
+// - *Terragrunt: The updated Terragrunt instance with the web identity configured.
+// - error: An error if the role ARN is empty.
+func (m *Terragrunt) WithAWSWebIdentity(
+	// token is the web identity token, e.g., the OIDC token issued by the CI provider.
+	token *dagger.Secret,
+	// roleArn is the ARN of the role to assume with the web identity token.
+	roleArn string,
+	// sessionName is the name of the role session. Default is "terragrunt".
+	// +optional
+	sessionName string,
+) (*Terragrunt, error) {
+	if roleArn == "" {
+		return nil, Errorf("the role ARN is required to configure the AWS web identity")
+	}
+
+	if sessionName == "" {
+		sessionName = terragruntCtrUser
+	}
+
+	m.withCredentialsFile(awsWebIdentityTokenFile, token, "AWS_WEB_IDENTITY_TOKEN_FILE")
+
+	m.Ctr = m.Ctr.
+		WithEnvVariable("AWS_ROLE_ARN", roleArn).
+		WithEnvVariable("AWS_ROLE_SESSION_NAME", sessionName)
+
+	return m, nil
+}
+
+// WithGCPCredentials mounts a GCP credentials file (service account key, or external account
+// configuration for workload identity federation) in the container.
+//
+// The file is mounted as a Dagger secret, and its path is set in the GOOGLE_APPLICATION_CREDENTIALS
+// environment variable.
+//
+// Parameters:
+// - credentialsFile: The GCP credentials file.
+// - project: The GCP project to use. It's set in the GOOGLE_PROJECT environment variable. Optional parameter.
+//
+// Returns:
+// - *Terragrunt: The updated Terragrunt instance with the GCP credentials mounted.
+func (m *Terragrunt) WithGCPCredentials(
+	// credentialsFile is the GCP credentials file.
+	credentialsFile *dagger.Secret,
+	// project is the GCP project to use. It's set in the GOOGLE_PROJECT environment variable.
+	// +optional
+	project string,
+) *Terragrunt {
+	m.withCredentialsFile(gcpCredentialsFile, credentialsFile, "GOOGLE_APPLICATION_CREDENTIALS")
+
+	if project != "" {
+		m.Ctr = m.Ctr.
+			WithEnvVariable("GOOGLE_PROJECT", project)
+	}
+
+	return m
+}
+
+// WithAzureCredentials configures the Azure credentials used by the azurerm provider and backend.
+//
+// Exactly one authentication method must be passed: a client certificate file (with an optional
+// password), an OIDC token file, or a client secret. Files are mounted as Dagger secrets, and
+// their paths are set in the ARM_CLIENT_CERTIFICATE_PATH or ARM_OIDC_TOKEN_FILE_PATH environment
+// variables. The client secret and the certificate password are set as secret variables. All of
+// them are registered to be redacted from the outputs and logs returned by the module.
+//
+// Parameters:
+// - tenantID: The Azure tenant ID.
+// - clientID: The Azure client (application) ID.
+// - subscriptionID: The Azure subscription ID. Optional parameter.
+// - clientCertificate: The client certificate file (PFX). Optional parameter.
+// - clientCertificatePassword: The password of the client certificate. Optional parameter.
+// - oidcToken: The OIDC token issued by the CI provider. Optional parameter.
+// - clientSecret: The client secret. Optional parameter.
+//
+// Returns:
+// - *Terragrunt: The updated Terragrunt instance with the Azure credentials configured.
+// - error: An error if the IDs are empty, or if not exactly one authentication method is passed.
+//
+//nolint:cyclop // It's okay, each authentication method is validated separately.
+func (m *Terragrunt) WithAzureCredentials(
+	// tenantID is the Azure tenant ID.
+	tenantID string,
+	// clientID is the Azure client (application) ID.
+	clientID string,
+	// subscriptionID is the Azure subscription ID.
+	// +optional
+	subscriptionID string,
+	// clientCertificate is the client certificate file (PFX).
+	// +optional
+	clientCertificate *dagger.Secret,
+	// clientCertificatePassword is the password of the client certificate.
+	// +optional
+	clientCertificatePassword *dagger.Secret,
+	// oidcToken is the OIDC token issued by the CI provider.
+	// +optional
+	oidcToken *dagger.Secret,
+	// clientSecret is the client secret.
+	// +optional
+	clientSecret *dagger.Secret,
+) (*Terragrunt, error) {
+	if tenantID == "" || clientID == "" {
+		return nil, Errorf("the tenant ID and the client ID are required to configure the Azure credentials")
+	}
+
+	authMethods := 0
+
+	for _, secret := range []*dagger.Secret{clientCertificate, oidcToken, clientSecret} {
+		if secret != nil {
+			authMethods++
+		}
+	}
+
+	if authMethods != 1 {
+		return nil, Errorf("exactly one of the client certificate, the OIDC token or the client secret is required, got %d",
+			authMethods)
+	}
+
+	m.Ctr = m.Ctr.
+		WithEnvVariable("ARM_TENANT_ID", tenantID).
+		WithEnvVariable("ARM_CLIENT_ID", clientID)
+
+	if subscriptionID != "" {
+		m.Ctr = m.Ctr.
+			WithEnvVariable("ARM_SUBSCRIPTION_ID", subscriptionID)
+	}
+
+	if clientCertificate != nil {
+		m.withCredentialsFile(azureCertificateFile, clientCertificate, "ARM_CLIENT_CERTIFICATE_PATH")
+
+		if clientCertificatePassword != nil {
+			m.Ctr = m.Ctr.
+				WithSecretVariable("ARM_CLIENT_CERTIFICATE_PASSWORD", clientCertificatePassword)
+
+			m.registerSecretsToRedact([]*dagger.Secret{clientCertificatePassword})
+		}
+	}
+
+	if oidcToken != nil {
+		m.withCredentialsFile(azureOIDCTokenFile, oidcToken, "ARM_OIDC_TOKEN_FILE_PATH")
+		m.Ctr = m.Ctr.
+			WithEnvVariable("ARM_USE_OIDC", "true")
+	}
+
+	if clientSecret != nil {
+		m.Ctr = m.Ctr.
+			WithSecretVariable("ARM_CLIENT_SECRET", clientSecret)
+
+		m.registerSecretsToRedact([]*dagger.Secret{clientSecret})
+	}
+
+	return m, nil
+}
+
+// WithSecretsAsEnvVars sets the given secrets as secret variables in the container.
+//
+// Each secret is exposed as an environment variable named after the secret, so its value is
+// never stored in the container configuration nor printed in the logs. The secrets are also
+// registered to be redacted from the outputs and logs returned by the module.
+//
+// Parameters:
+// - ctx: The context to use when resolving the secret names.
+// - secrets: The secrets to set in the container.
+//
+// Returns:
+// - *Terragrunt: The updated Terragrunt instance with the secrets set.
+// - error: An error if the name of a secret can't be resolved.
+func (m *Terragrunt) WithSecretsAsEnvVars(
+	// ctx is the context to use when resolving the secret names.
+	ctx context.Context,
+	// secrets are the secrets to set in the container.
+	secrets []*dagger.Secret,
+) (*Terragrunt, error) {
+	for _, secret := range secrets {
+		secretName, err := secret.Name(ctx)
+		if err != nil {
+			return nil, WrapError(err, "failed to get the name of the secret")
+		}
+
+		if secretName == "" {
+			return nil, Errorf("the secret name is empty, can't set it as an environment variable")
+		}
+
+		m.Ctr = m.Ctr.
+			WithSecretVariable(secretName, secret)
+	}
+
+	m.registerSecretsToRedact(secrets)
+
+	return m, nil
+}
+
+// withCredentialsFile mounts the given secret as a read-only file owned by the terragrunt user,
+// and sets its path in the given environment variable. The content of the file is registered to
+// be redacted from the outputs and logs returned by the module.
+func (m *Terragrunt) withCredentialsFile(path string, secret *dagger.Secret, envVarKey string) {
+	m.Ctr = m.Ctr.
+		WithMountedSecret(path, secret, dagger.ContainerWithMountedSecretOpts{
+			Owner: terragruntCtrUser,
+			Mode:  credentialsFileMode,
+		}).
+		WithoutEnvVariable(envVarKey).
+		WithEnvVariable(envVarKey, path)
+
+	m.registerSecretsToRedact([]*dagger.Secret{secret})
+}
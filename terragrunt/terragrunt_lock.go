@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
+	"github.com/Excoriate/daggerx/pkg/fixtures"
+)
+
+const (
+	// lockFileName is the name of the dependency lock file of Terraform and OpenTofu.
+	lockFileName = ".terraform.lock.hcl"
+)
+
+// lockFileHashRegex matches the hashes of the providers in a lock file, e.g., "h1:..." or "zh:...".
+var lockFileHashRegex = regexp.MustCompile(`"((?:h1|zh):[^"]+)"`)
+
+// defaultLockPlatforms returns the platforms the lock files are generated for when none is passed.
+func defaultLockPlatforms() []string {
+	return []string{"linux_amd64", "linux_arm64", "darwin_amd64", "darwin_arm64"}
+}
+
+// ProvidersLock runs 'providers lock' for several platforms, and returns the updated lock files.
+//
+// With Terragrunt (the default tool), the lock file of every unit is updated. The units are discovered
+// with 'output-module-groups', unless they're passed explicitly. With Terraform or OpenTofu, the lock
+// files of the given modules (or of the root of the source directory) are updated, which covers setups
+// with a shared .terraform.lock.hcl file.
+//
+// The returned directory contains the updated .terraform.lock.hcl files, at the same relative paths as
+// in the source directory, so they can be exported and committed.
+//
+// In check mode, the lock files aren't returned: the function fails if any lock file is missing, or if it's
+// missing hashes for the required platforms (i.e., 'providers lock' would add hashes to it).
+//
+// Parameters:
+// - ctx: The context to use when executing the command.
+// - source: The source directory that includes the units or modules.
+// - platforms: The platforms to lock the providers for. Default is linux and darwin, amd64 and arm64.
+// - modules: The units or modules whose lock files are updated, relative to the source. Optional parameter.
+// - tool: The tool to use for executing the command. Default is "terragrunt". Optional parameter.
+// - check: Whether to only check that the lock files are complete. Optional parameter.
+// - envVars: The environment variables to pass to the container. Optional parameter.
+// - secrets: The secrets to pass to the container. Optional parameter.
+//
+// Returns:
+// - *dagger.Directory: The directory with the updated lock files.
+// - error: An error if the command fails, or if a lock file is incomplete in check mode.
+//
+//nolint:funlen,lll // It's okay, the workflow is sequential and the ignore pattern is included.
+func (m *Terragrunt) ProvidersLock(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// source is the source directory that includes the units or modules.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// platforms are the platforms to lock the providers for, e.g., "linux_amd64", "darwin_arm64".
+	// +optional
+	platforms []string,
+	// modules are the units or modules whose lock files are updated, relative to the source directory.
+	// +optional
+	modules []string,
+	// tool is the tool to use for executing the command. Default is "terragrunt".
+	// +optional
+	tool string,
+	// check is whether to only check that the lock files are complete for the required platforms.
+	// +optional
+	check bool,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+) (*dagger.Directory, error) {
+	if len(platforms) == 0 {
+		platforms = defaultLockPlatforms()
+	}
+
+	if tool == "" {
+		tool = string(TerragruntTool)
+	}
+
+	lockArgs := []string{"lock"}
+	for _, platform := range platforms {
+		lockArgs = append(lockArgs, "-platform="+strings.TrimSpace(platform))
+	}
+
+	lockCmd, err := m.prepareExec(ctx, "providers", lockArgs, false, source, "", envVars, secrets, tool)
+	if err != nil {
+		return nil, WrapError(err, "failed to prepare the providers lock command")
+	}
+
+	// The provider cache server only stores the hashes of the current platform, so it's disabled.
+	baseCtr := m.
+		WithTerragruntProviderCacheServerDisabled().
+		Ctr.
+		WithEnvVariable("TERRAGRUNT_NON_INTERACTIVE", "true")
+
+	if len(modules) == 0 {
+		modules = []string{"."}
+
+		if tool == string(TerragruntTool) {
+			groups, err := discoverUnitGroups(ctx, baseCtr, tool, "providers")
+			if err != nil {
+				return nil, err
+			}
+
+			modules = nil
+			for _, group := range groups {
+				modules = append(modules, group...)
+			}
+		}
+	}
+
+	sort.Strings(modules)
+
+	var (
+		lockFiles  = dag.Directory()
+		incomplete []string
+	)
+
+	for _, module := range modules {
+		lockFilePath := filepath.Join(module, lockFileName)
+
+		updatedLockFile := baseCtr.
+			WithWorkdir(filepath.Join(fixtures.MntPrefix, module)).
+			WithExec(lockCmd).
+			File(lockFileName)
+
+		if check {
+			isComplete, reason, err := isLockFileComplete(ctx, source, lockFilePath, updatedLockFile)
+			if err != nil {
+				return nil, err
+			}
+
+			if !isComplete {
+				incomplete = append(incomplete, lockFilePath+" ("+reason+")")
+			}
+
+			continue
+		}
+
+		lockFiles = lockFiles.WithFile(lockFilePath, updatedLockFile)
+	}
+
+	if len(incomplete) > 0 {
+		return nil, Errorf("the lock files are incomplete for the platforms %s:\n%s",
+			strings.Join(platforms, ", "), strings.Join(incomplete, "\n"))
+	}
+
+	return lockFiles, nil
+}
+
+// isLockFileComplete compares the lock file of the source directory with the one updated by 'providers lock'.
+// The lock file is complete if it exists, and the update didn't add any hash to it. Otherwise, the reason is returned.
+func isLockFileComplete(
+	ctx context.Context,
+	source *dagger.Directory,
+	lockFilePath string,
+	updatedLockFile *dagger.File,
+) (bool, string, error) {
+	existing, err := source.Glob(ctx, lockFilePath)
+	if err != nil {
+		return false, "", WrapErrorf(err, "failed to look for the lock file: %s", lockFilePath)
+	}
+
+	if len(existing) == 0 {
+		return false, "missing lock file", nil
+	}
+
+	current, err := source.File(lockFilePath).Contents(ctx)
+	if err != nil {
+		return false, "", WrapErrorf(err, "failed to read the lock file: %s", lockFilePath)
+	}
+
+	updated, err := updatedLockFile.Contents(ctx)
+	if err != nil {
+		return false, "", WrapErrorf(err, "failed to read the updated lock file: %s", lockFilePath)
+	}
+
+	missing := missingLockFileHashes(current, updated)
+	if len(missing) > 0 {
+		return false, fmt.Sprintf("missing %d hash(es)", len(missing)), nil
+	}
+
+	return true, "", nil
+}
+
+// missingLockFileHashes returns the hashes of the updated lock file that aren't in the current one.
+func missingLockFileHashes(current, updated string) []string {
+	currentHashes := map[string]bool{}
+	for _, match := range lockFileHashRegex.FindAllStringSubmatch(current, -1) {
+		currentHashes[match[1]] = true
+	}
+
+	var missing []string
+
+	for _, match := range lockFileHashRegex.FindAllStringSubmatch(updated, -1) {
+		if !currentHashes[match[1]] {
+			missing = append(missing, match[1])
+		}
+	}
+
+	return missing
+}
@@ -1,12 +1,28 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"strconv"
 	"strings"
 
 	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
 )
 
+const (
+	// execLogsDir is the directory where the logs of a command executed with ExecWithLogs are written.
+	execLogsDir        = "/var/log/iac"
+	tfLogFileName      = "terraform.log"
+	tgLogFileName      = "terragrunt.log"
+	stdoutLogFileName  = "stdout.log"
+	exitCodeFileName   = "exit-code"
+	tfJSONLogFileName  = "terraform-log.json"
+	tfLogLevelJSON     = "JSON"
+	tfLogLevelDefault  = "INFO"
+	tfLogJSONLineStart = "{"
+)
+
 // TfLogsConfig holds the configuration for Terraform logs.
 type TfLogsConfig struct {
 	// TfLog is the log level for Terraform.
@@ -29,6 +45,29 @@ type TgLogsConfig struct {
 	TgLogShowAbsPaths bool
 }
 
+// TfLogEntry represents a structured entry of the Terraform logs, when TF_LOG is set to JSON.
+type TfLogEntry struct {
+	// Level is the log level of the entry, e.g., "info", "debug".
+	Level string
+	// Message is the message of the entry.
+	Message string
+	// Module is the module that emitted the entry, e.g., "provider.terraform-provider-aws".
+	Module string
+	// Timestamp is the timestamp of the entry.
+	Timestamp string
+	// Caller is the source location that emitted the entry.
+	Caller string
+}
+
+// tfLogJSONLine is a line of the Terraform logs, as written by Terraform when TF_LOG is set to JSON.
+type tfLogJSONLine struct {
+	Level     string `json:"@level"`
+	Message   string `json:"@message"`
+	Module    string `json:"@module"`
+	Timestamp string `json:"@timestamp"`
+	Caller    string `json:"@caller"`
+}
+
 // LogsConfig holds the configuration for both Terraform and Terragrunt logs.
 type LogsConfig struct {
 	// TfLogs holds the configuration for Terraform logs.
@@ -152,3 +191,79 @@ func (l *LogsConfig) WithTerragruntLogsSetInContainer(ctr *dagger.Container) *da
 
 	return ctr
 }
+
+// ParseTerraformJSONLogs parses a Terraform log file written with TF_LOG=JSON into structured entries.
+//
+// Lines that aren't valid JSON objects (e.g., panics or plain-text output) are skipped.
+//
+// Parameters:
+// - ctx: The context to use when reading the log file.
+// - logFile: The Terraform log file to parse.
+//
+// Returns:
+// - []TfLogEntry: The structured log entries.
+// - error: An error if the log file can't be read.
+func (m *Terragrunt) ParseTerraformJSONLogs(
+	// ctx is the context to use when reading the log file.
+	ctx context.Context,
+	// logFile is the terraform log file to parse.
+	logFile *dagger.File,
+) ([]TfLogEntry, error) {
+	content, err := logFile.Contents(ctx)
+	if err != nil {
+		return nil, WrapError(err, "failed to read the terraform log file")
+	}
+
+	return parseTerraformJSONLogs(content), nil
+}
+
+// parseTerraformJSONLogs parses the content of a Terraform log file written with TF_LOG=JSON.
+// It skips the lines that can't be decoded as a log entry.
+func parseTerraformJSONLogs(content string) []TfLogEntry {
+	entries := []TfLogEntry{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+
+	// Provider debug logs can include very long lines (e.g., full API responses).
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 10*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, tfLogJSONLineStart) {
+			continue
+		}
+
+		var jsonLine tfLogJSONLine
+		if err := json.Unmarshal([]byte(line), &jsonLine); err != nil {
+			continue
+		}
+
+		entries = append(entries, TfLogEntry(jsonLine))
+	}
+
+	return entries
+}
+
+// resolveTerraformLogLevel returns the terraform log level to use, in upper case.
+// If no log level is passed, the TF_LOG value set in the container is used, or INFO if it's not set.
+func (m *Terragrunt) resolveTerraformLogLevel(ctx context.Context, tfLog string) (string, error) {
+	if tfLog == "" {
+		currentTfLog, err := m.Ctr.EnvVariable(ctx, "TF_LOG")
+		if err != nil {
+			return "", WrapError(err, "failed to get the TF_LOG environment variable")
+		}
+
+		tfLog = currentTfLog
+	}
+
+	if tfLog == "" {
+		return tfLogLevelDefault, nil
+	}
+
+	tfLog = strings.ToUpper(strings.TrimSpace(tfLog))
+
+	if err := validateTerraformLogLevel(tfLog); err != nil {
+		return "", err
+	}
+
+	return tfLog, nil
+}
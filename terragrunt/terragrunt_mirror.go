@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
+)
+
+const (
+	// terraformProvidersMirrorDir is the default path where the providers mirror is written or mounted.
+	terraformProvidersMirrorDir = "/home/.terraform.d/providers-mirror"
+	// terraformCliConfigFile is the path of the Terraform CLI configuration file written by the module.
+	terraformCliConfigFile = "/home/terragrunt/.terraformrc"
+	// providersMirrorCacheVolumeDefault is the default name of the cache volume that holds the providers mirror.
+	providersMirrorCacheVolumeDefault = "terragrunt-providers-mirror"
+)
+
+// ProvidersMirror runs 'providers mirror' for the given stack and returns the mirror as a directory.
+//
+// The command is executed through the selected tool (Terragrunt by default), so the
+// Terraform source referenced by the terragrunt.hcl file is downloaded before the
+// providers are mirrored. The returned directory can be exported, committed or passed
+// to WithProvidersFilesystemMirror to run later commands without registry access.
+//
+// Parameters:
+// - source: The source directory that includes the source code.
+// - module: The module (or terragrunt unit) to mirror the providers from. Optional parameter.
+// - platforms: The target platforms to mirror, e.g., "linux_amd64". Optional parameter.
+// - tool: The tool to use for executing the command. Optional parameter.
+//
+// Returns:
+// - *dagger.Directory: The directory containing the providers mirror.
+// - error: An error if the command can't be built.
+//
+//nolint:lll // It's okay, since the ignore pattern is included.
+func (m *Terragrunt) ProvidersMirror(
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
+	// +optional
+	module string,
+	// platforms is the list of target platforms to mirror, e.g., "linux_amd64", "darwin_arm64".
+	// +optional
+	platforms []string,
+	// tool is the tool to use for executing the command.
+	// +optional
+	tool string,
+) (*dagger.Directory, error) {
+	mirrorCmd, err := m.newProvidersMirrorCmd(terraformProvidersMirrorDir, platforms, tool)
+	if err != nil {
+		return nil, WrapError(err, "failed to build the providers mirror command")
+	}
+
+	ctr := m.
+		WithSource(source, module, terragruntCtrUser).
+		Ctr.
+		WithExec(mirrorCmd)
+
+	return ctr.Directory(terraformProvidersMirrorDir), nil
+}
+
+// WithProvidersMirrorCached runs 'providers mirror' for the given stack into a cache volume.
+//
+// The cache volume is mounted at the providers mirror path and remains mounted in the
+// container, so it can be reused by WithProvidersFilesystemMirror (passing the same
+// cache volume name) in subsequent runs.
+//
+// Parameters:
+// - source: The source directory that includes the source code.
+// - module: The module (or terragrunt unit) to mirror the providers from. Optional parameter.
+// - platforms: The target platforms to mirror, e.g., "linux_amd64". Optional parameter.
+// - cacheVolumeName: The name of the cache volume. Optional parameter.
+// - tool: The tool to use for executing the command. Optional parameter.
+//
+// Returns:
+// - *Terragrunt: The updated Terragrunt instance with the providers mirror populated.
+// - error: An error if the command can't be built.
+//
+//nolint:lll // It's okay, since the ignore pattern is included.
+func (m *Terragrunt) WithProvidersMirrorCached(
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
+	// +optional
+	module string,
+	// platforms is the list of target platforms to mirror, e.g., "linux_amd64", "darwin_arm64".
+	// +optional
+	platforms []string,
+	// cacheVolumeName is the name of the cache volume. Default is "terragrunt-providers-mirror".
+	// +optional
+	cacheVolumeName string,
+	// tool is the tool to use for executing the command.
+	// +optional
+	tool string,
+) (*Terragrunt, error) {
+	mirrorCmd, err := m.newProvidersMirrorCmd(terraformProvidersMirrorDir, platforms, tool)
+	if err != nil {
+		return nil, WrapError(err, "failed to build the providers mirror command")
+	}
+
+	if cacheVolumeName == "" {
+		cacheVolumeName = providersMirrorCacheVolumeDefault
+	}
+
+	m.Ctr = m.
+		WithSource(source, module, terragruntCtrUser).
+		Ctr.
+		WithMountedCache(terraformProvidersMirrorDir, dag.CacheVolume(cacheVolumeName), dagger.ContainerWithMountedCacheOpts{
+			Owner:   terragruntCtrUser,
+			Sharing: dagger.Shared,
+		}).
+		WithExec(mirrorCmd)
+
+	return m, nil
+}
+
+// WithProvidersFilesystemMirror configures Terraform to install providers only from a filesystem mirror.
+//
+// It writes a .terraformrc file with a 'provider_installation { filesystem_mirror }' block and
+// sets the TF_CLI_CONFIG_FILE environment variable to it. The Terragrunt provider cache server
+// is disabled, since it requires registry access. The mirror can be passed as a directory, or
+// as the name of a cache volume previously populated with WithProvidersMirrorCached. If none is
+// passed, the mirror path is expected to exist in the container already.
+//
+// Parameters:
+// - mirror: The directory containing the providers mirror. Optional parameter.
+// - cacheVolumeName: The name of the cache volume containing the providers mirror. Optional parameter.
+// - mirrorPath: The path where the mirror is mounted in the container. Optional parameter.
+//
+// Returns:
+// - *Terragrunt: The updated Terragrunt instance configured to use the filesystem mirror.
+// - error: An error if both the mirror directory and the cache volume name are passed.
+func (m *Terragrunt) WithProvidersFilesystemMirror(
+	// mirror is the directory containing the providers mirror.
+	// +optional
+	mirror *dagger.Directory,
+	// cacheVolumeName is the name of the cache volume containing the providers mirror.
+	// +optional
+	cacheVolumeName string,
+	// mirrorPath is the path where the mirror is mounted in the container.
+	// Default is "/home/.terraform.d/providers-mirror".
+	// +optional
+	mirrorPath string,
+) (*Terragrunt, error) {
+	if mirror != nil && cacheVolumeName != "" {
+		return nil, Errorf("either the mirror directory or the cache volume name can be passed, not both")
+	}
+
+	if mirrorPath == "" {
+		mirrorPath = terraformProvidersMirrorDir
+	}
+
+	if mirror != nil {
+		m.Ctr = m.Ctr.
+			WithMountedDirectory(mirrorPath, mirror, dagger.ContainerWithMountedDirectoryOpts{
+				Owner: terragruntCtrUser,
+			})
+	}
+
+	if cacheVolumeName != "" {
+		m.Ctr = m.Ctr.
+			WithMountedCache(mirrorPath, dag.CacheVolume(cacheVolumeName), dagger.ContainerWithMountedCacheOpts{
+				Owner:   terragruntCtrUser,
+				Sharing: dagger.Shared,
+			})
+	}
+
+	m.Ctr = m.Ctr.
+		WithNewFile(terraformCliConfigFile, newFilesystemMirrorCliConfig(mirrorPath), dagger.ContainerWithNewFileOpts{
+			Owner: terragruntCtrUser,
+		}).
+		WithoutEnvVariable("TF_CLI_CONFIG_FILE").
+		WithEnvVariable("TF_CLI_CONFIG_FILE", terraformCliConfigFile)
+
+	return m.WithTerragruntProviderCacheServerDisabled(), nil
+}
+
+// newProvidersMirrorCmd builds the 'providers mirror' command for the given tool.
+// If no tool is passed, the Terragrunt entrypoint is used.
+func (m *Terragrunt) newProvidersMirrorCmd(mirrorPath string, platforms []string, tool string) ([]string, error) {
+	entrypoint := m.Tg.getEntrypoint()
+
+	if tool != "" {
+		if err := IsValidIACTool(tool); err != nil {
+			return nil, WrapErrorf(err, "failed to set the entrypoint with tool: %s", tool)
+		}
+
+		entrypoint = tool
+	}
+
+	cmd := []string{entrypoint, "providers", "mirror"}
+
+	for _, platform := range platforms {
+		cmd = append(cmd, "-platform="+strings.TrimSpace(platform))
+	}
+
+	return append(cmd, mirrorPath), nil
+}
+
+// newFilesystemMirrorCliConfig returns the content of a Terraform CLI configuration file
+// that installs every provider from the filesystem mirror located at mirrorPath.
+func newFilesystemMirrorCliConfig(mirrorPath string) string {
+	return fmt.Sprintf(`provider_installation {
+  filesystem_mirror {
+    path    = %q
+    include = ["*/*"]
+  }
+}
+`, mirrorPath)
+}
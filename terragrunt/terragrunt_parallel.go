@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
+	"github.com/Excoriate/daggerx/pkg/fixtures"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// defaultUnitsConcurrency is the default number of units executed at the same time within a group.
+	defaultUnitsConcurrency = 4
+	unitsSummaryFileName    = "summary.json"
+	unitStatusSucceeded     = "succeeded"
+	unitStatusFailed        = "failed"
+	unitStatusSkipped       = "skipped"
+)
+
+// UnitRunResult represents the result of executing a command in a single Terragrunt unit.
+type UnitRunResult struct {
+	// Unit is the path of the unit, relative to the source directory.
+	Unit string
+	// Group is the position (starting at 1) of the group the unit belongs to, in execution order.
+	Group int
+	// Status is the status of the unit: "succeeded", "failed" or "skipped".
+	Status string
+	// ExitCode is the exit code of the command. It's -1 if the unit was skipped.
+	ExitCode int
+}
+
+// RunAllParallel executes a command in every unit of the source directory, running each unit in its own container.
+//
+// Instead of delegating 'run-all' to Terragrunt inside a single container, the units and their dependency
+// order are discovered with 'output-module-groups'. The groups are executed in order, and the units of
+// the same group are executed concurrently, in separate containers. If a unit fails, the following groups
+// are skipped, since they might depend on it.
+//
+// Each unit runs with the whole source directory mounted, so dependencies are resolved as usual. Since the
+// containers are isolated, the units should use a remote state backend for dependency outputs to be available.
+//
+// The returned directory contains, for each unit, a directory with the same relative path, containing:
+// - stdout.log: The stdout of the command.
+// - terragrunt.log: The Terragrunt logs (stderr).
+// - exit-code: The exit code of the command.
+// And a summary.json file with the result of every unit. The values of the secrets, and the redaction
+// patterns configured with WithRedaction, are redacted from the logs.
+//
+// Parameters:
+// - ctx: The context to use when executing the command.
+// - command: The command to execute in each unit, e.g., "plan". It can't be 'run-all'.
+// - args: The arguments to pass to the command. Optional parameter.
+// - autoApprove: The flag to auto approve the command. Optional parameter.
+// - source: The source directory that includes the Terragrunt units.
+// - envVars: The environment variables to pass to the containers. Optional parameter.
+// - secrets: The secrets to pass to the containers. Optional parameter.
+// - concurrency: The maximum number of units executed at the same time within a group. Default is 4.
+// - failOnUnitError: Whether to return an error if any unit fails. Optional parameter.
+//
+// Returns:
+// - *dagger.Directory: The directory with the logs of each unit and the summary.
+// - error: An error if the units can't be discovered, or if a unit fails and failOnUnitError is set.
+//
+//nolint:funlen,lll // It's okay, the workflow is sequential and the ignore pattern is included.
+func (m *Terragrunt) RunAllParallel(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// command is the command to execute in each unit, e.g., "plan". It can't be 'run-all'.
+	command string,
+	// args are the arguments to pass to the command.
+	// +optional
+	args []string,
+	// autoApprove is the flag to auto approve the command.
+	// +optional
+	autoApprove bool,
+	// source is the source directory that includes the terragrunt units.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// envVars is the environment variables to pass to the containers.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the containers.
+	// +optional
+	secrets []*dagger.Secret,
+	// concurrency is the maximum number of units executed at the same time within a group. Default is 4.
+	// +optional
+	concurrency int,
+	// failOnUnitError is whether to return an error if any unit fails.
+	// +optional
+	failOnUnitError bool,
+) (*dagger.Directory, error) {
+	if strings.HasPrefix(strings.TrimSpace(command), "run-all") {
+		return nil, Errorf("the command can't be 'run-all', it's executed in each unit separately")
+	}
+
+	if concurrency <= 0 {
+		concurrency = defaultUnitsConcurrency
+	}
+
+	cmd, err := m.prepareExec(ctx, command, args, autoApprove, source, "", envVars, secrets, "")
+	if err != nil {
+		return nil, WrapErrorf(err, "failed to prepare the command: %s", command)
+	}
+
+	baseCtr := m.Ctr.
+		WithEnvVariable("TERRAGRUNT_NON_INTERACTIVE", "true")
+
+	redactor, err := m.newRedactor(ctx)
+	if err != nil {
+		return nil, WrapError(err, "failed to configure the redaction of the logs")
+	}
+
+	groups, err := discoverUnitGroups(ctx, baseCtr, m.Tg.getEntrypoint(), command)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		results  []UnitRunResult
+		logsDir  = dag.Directory()
+		failed   []string
+		skipping bool
+	)
+
+	for groupIdx, units := range groups {
+		groupResults := make([]UnitRunResult, len(units))
+		groupLogs := make([]*dagger.Directory, len(units))
+
+		if skipping {
+			for unitIdx, unit := range units {
+				groupResults[unitIdx] = UnitRunResult{
+					Unit:     unit,
+					Group:    groupIdx + 1,
+					Status:   unitStatusSkipped,
+					ExitCode: -1,
+				}
+			}
+
+			results = append(results, groupResults...)
+
+			continue
+		}
+
+		runner, runnerCtx := errgroup.WithContext(ctx)
+		runner.SetLimit(concurrency)
+
+		for unitIdx, unit := range units {
+			runner.Go(func() error {
+				result, unitLogs, err := runUnit(runnerCtx, baseCtr, cmd, unit, redactor)
+				if err != nil {
+					return WrapErrorf(err, "failed to execute the command in unit: %s", unit)
+				}
+
+				result.Group = groupIdx + 1
+
+				// Each unit writes to its own index, so no lock is needed.
+				groupResults[unitIdx] = result
+				groupLogs[unitIdx] = unitLogs
+
+				return nil
+			})
+		}
+
+		if err := runner.Wait(); err != nil {
+			return nil, err
+		}
+
+		for unitIdx, result := range groupResults {
+			logsDir = logsDir.WithDirectory(result.Unit, groupLogs[unitIdx])
+
+			if result.Status == unitStatusFailed {
+				failed = append(failed, result.Unit)
+				skipping = true
+			}
+		}
+
+		results = append(results, groupResults...)
+	}
+
+	summaryAsJSON, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return nil, WrapError(err, "failed to marshal the units summary")
+	}
+
+	if failOnUnitError && len(failed) > 0 {
+		return nil, Errorf("the command %s failed in %d unit(s): %s", command, len(failed), strings.Join(failed, ", "))
+	}
+
+	return logsDir.
+		WithNewFile(unitsSummaryFileName, string(summaryAsJSON)), nil
+}
+
+// runUnit executes the command in its own container, with the unit as the working directory, and
+// returns the result and the redacted logs of the unit. A failing command isn't an error, it's reported in the result.
+func runUnit(
+	ctx context.Context,
+	baseCtr *dagger.Container,
+	cmd []string,
+	unit string,
+	redactor *redactor,
+) (UnitRunResult, *dagger.Directory, error) {
+	ctr := baseCtr.
+		WithWorkdir(filepath.Join(fixtures.MntPrefix, unit)).
+		WithExec(cmd, dagger.ContainerWithExecOpts{
+			Expect: dagger.ReturnTypeAny,
+		})
+
+	exitCode, err := ctr.ExitCode(ctx)
+	if err != nil {
+		return UnitRunResult{}, nil, WrapError(err, "failed to get the exit code")
+	}
+
+	stdout, err := ctr.Stdout(ctx)
+	if err != nil {
+		return UnitRunResult{}, nil, WrapError(err, "failed to get stdout")
+	}
+
+	stderr, err := ctr.Stderr(ctx)
+	if err != nil {
+		return UnitRunResult{}, nil, WrapError(err, "failed to get stderr")
+	}
+
+	status := unitStatusSucceeded
+	if exitCode != 0 {
+		status = unitStatusFailed
+	}
+
+	unitLogs := dag.
+		Directory().
+		WithNewFile(stdoutLogFileName, redactor.redact(stdout)).
+		WithNewFile(tgLogFileName, redactor.redact(stderr)).
+		WithNewFile(exitCodeFileName, strconv.Itoa(exitCode))
+
+	return UnitRunResult{
+		Unit:     unit,
+		Status:   status,
+		ExitCode: exitCode,
+	}, unitLogs, nil
+}
+
+// discoverUnitGroups runs 'output-module-groups' in the source directory, and returns the units
+// grouped in execution order. The units are relative to the source directory. For 'destroy', the
+// groups are returned in the destroy order.
+func discoverUnitGroups(
+	ctx context.Context,
+	ctr *dagger.Container,
+	entrypoint, command string,
+) ([][]string, error) {
+	discoverCmd := []string{entrypoint, "output-module-groups"}
+	if strings.HasPrefix(strings.TrimSpace(command), "destroy") {
+		discoverCmd = append(discoverCmd, "destroy")
+	}
+
+	groupsAsJSON, err := ctr.
+		WithWorkdir(fixtures.MntPrefix).
+		WithExec(discoverCmd).
+		Stdout(ctx)
+
+	if err != nil {
+		return nil, WrapError(err, "failed to discover the units with output-module-groups")
+	}
+
+	groups, err := parseUnitGroups(groupsAsJSON, fixtures.MntPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(groups) == 0 {
+		return nil, Errorf("no terragrunt unit found in the source directory")
+	}
+
+	return groups, nil
+}
+
+// parseUnitGroups parses the output of 'output-module-groups', which maps "Group <n>" to the absolute
+// paths of the units, into groups sorted by their position. The paths are made relative to the root.
+func parseUnitGroups(groupsAsJSON, root string) ([][]string, error) {
+	var rawGroups map[string][]string
+	if err := json.Unmarshal([]byte(groupsAsJSON), &rawGroups); err != nil {
+		return nil, WrapError(err, "failed to parse the output of output-module-groups")
+	}
+
+	positions := make(map[int][]string, len(rawGroups))
+
+	for name, units := range rawGroups {
+		position, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(name, "Group")))
+		if err != nil {
+			return nil, WrapErrorf(err, "unexpected group name in the output of output-module-groups: %s", name)
+		}
+
+		relUnits := make([]string, 0, len(units))
+
+		for _, unit := range units {
+			relUnit, err := filepath.Rel(root, unit)
+			if err != nil {
+				return nil, WrapErrorf(err, "failed to resolve the path of the unit: %s", unit)
+			}
+
+			relUnits = append(relUnits, relUnit)
+		}
+
+		sort.Strings(relUnits)
+		positions[position] = relUnits
+	}
+
+	sortedPositions := make([]int, 0, len(positions))
+	for position := range positions {
+		sortedPositions = append(sortedPositions, position)
+	}
+
+	sort.Ints(sortedPositions)
+
+	groups := make([][]string, 0, len(sortedPositions))
+	for _, position := range sortedPositions {
+		groups = append(groups, positions[position])
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
+)
+
+const (
+	// redactedMask is the text that replaces the sensitive values.
+	redactedMask = "[REDACTED]"
+	// minRedactedSecretLength is the minimum length of a secret value to be redacted. Shorter values
+	// (e.g., "1" or "true") would mask unrelated parts of the output.
+	minRedactedSecretLength = 4
+	secretValueFindingName  = "secret-value"
+	scanPlanFileName        = "scan.tfplan"
+)
+
+// defaultRedactionPatterns are the patterns of well-known credentials redacted by default.
+//
+//nolint:gochecknoglobals // It's a read-only lookup table.
+var defaultRedactionPatterns = map[string]string{
+	"aws-access-key-id":     `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	"aws-secret-access-key": `(?i)aws_secret_access_key\s*[=:]\s*"?[A-Za-z0-9/+=]{40}"?`,
+	"github-token":          `\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`,
+	"gitlab-token":          `\bglpat-[A-Za-z0-9\-_]{20,}\b`,
+	"slack-token":           `\bxox[abprs]-[A-Za-z0-9\-]{10,}\b`,
+	"terraform-cloud-token": `\b[A-Za-z0-9]{14}\.atlasv1\.[A-Za-z0-9\-_=]{60,}`,
+	"bearer-token":          `(?i)\bbearer\s+[A-Za-z0-9\-._~+/]{16,}=*`,
+	"private-key":           `-----BEGIN [A-Z ]*PRIVATE KEY-----`,
+}
+
+// RedactionConfig holds the configuration to redact sensitive values from the outputs and logs.
+type RedactionConfig struct {
+	// Secrets are the secrets whose values are redacted.
+	Secrets []*dagger.Secret
+	// Patterns are extra regular expressions of sensitive values to redact.
+	Patterns []string
+	// DisableDefaultPatterns disables the built-in patterns (AWS keys, tokens, private keys).
+	DisableDefaultPatterns bool
+}
+
+// redactionPattern is a compiled pattern of sensitive values, with a name to report the findings.
+type redactionPattern struct {
+	name  string
+	regex *regexp.Regexp
+}
+
+// redactor masks sensitive values (secret values and patterns) in a text.
+type redactor struct {
+	values   []string
+	patterns []redactionPattern
+}
+
+// redactedExec is the result of a command executed without failing on its exit code.
+type redactedExec struct {
+	ctr      *dagger.Container
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+// WithRedaction configures the redaction of sensitive values in the outputs and logs returned by the module.
+//
+// The values of the secrets passed to the commands are always redacted. This function registers extra
+// secrets to redact, and regular expressions of sensitive values. By default, well-known credentials
+// (AWS access keys, GitHub, GitLab, Slack and Terraform Cloud tokens, bearer tokens and private keys)
+// are redacted as well.
+//
+// Parameters:
+// - secrets: Extra secrets whose values are redacted. Optional parameter.
+// - patterns: Extra regular expressions of sensitive values to redact. Optional parameter.
+// - disableDefaultPatterns: Whether to disable the built-in patterns. Optional parameter.
+//
+// Returns:
+// - *Terragrunt: The updated Terragrunt instance with the redaction configured.
+// - error: An error if a pattern isn't a valid regular expression.
+func (m *Terragrunt) WithRedaction(
+	// secrets are extra secrets whose values are redacted.
+	// +optional
+	secrets []*dagger.Secret,
+	// patterns are extra regular expressions of sensitive values to redact, e.g., "token=[a-z0-9]+".
+	// +optional
+	patterns []string,
+	// disableDefaultPatterns is whether to disable the built-in patterns of well-known credentials.
+	// +optional
+	disableDefaultPatterns bool,
+) (*Terragrunt, error) {
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, WrapErrorf(err, "invalid redaction pattern: %s", pattern)
+		}
+	}
+
+	m.registerSecretsToRedact(secrets)
+
+	m.Redaction.Patterns = append(m.Redaction.Patterns, patterns...)
+	m.Redaction.DisableDefaultPatterns = disableDefaultPatterns
+
+	return m, nil
+}
+
+// ScanPlan runs 'plan' for the given unit, and fails if any sensitive value is detected in its output.
+//
+// The plan output (stdout and stderr) is scanned for the values of the secrets and the redaction patterns
+// configured with WithRedaction. If a saved plan file is passed, its rendering with 'show' is scanned
+// instead. The returned output is always redacted, and the findings only name what was detected.
+//
+// Parameters:
+// - ctx: The context to use when executing the command.
+// - source: The source directory that includes the source code.
+// - module: The module or terragrunt unit to plan. Optional parameter.
+// - planFile: A saved plan file to scan, instead of running a new plan. Optional parameter.
+// - args: Extra arguments to pass to the plan command. Optional parameter.
+// - envVars: The environment variables to pass to the container. Optional parameter.
+// - secrets: The secrets to pass to the container. Optional parameter.
+//
+// Returns:
+// - string: The redacted plan output.
+// - error: An error if the plan fails, or if a sensitive value is detected.
+//
+//nolint:lll // It's okay, since the ignore pattern is included.
+func (m *Terragrunt) ScanPlan(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
+	// +optional
+	module string,
+	// planFile is a saved plan file to scan, instead of running a new plan.
+	// +optional
+	planFile *dagger.File,
+	// args are extra arguments to pass to the plan command.
+	// +optional
+	args []string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+) (string, error) {
+	command, cmdArgs := "plan", append(slices.Clone(args), "-no-color")
+
+	if planFile != nil {
+		scanPlanPath := filepath.Join(plansDir, scanPlanFileName)
+		command, cmdArgs = "show", []string{"-no-color", scanPlanPath}
+
+		m.withPlansDir()
+		m.Ctr = m.Ctr.
+			WithMountedFile(scanPlanPath, planFile, dagger.ContainerWithMountedFileOpts{
+				Owner: terragruntCtrUser,
+			})
+	}
+
+	cmd, err := m.prepareExec(ctx, command, cmdArgs, false, source, module, envVars, secrets, "")
+	if err != nil {
+		return "", WrapErrorf(err, "failed to prepare the %s command", command)
+	}
+
+	redactor, err := m.newRedactor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := runRedactedExec(ctx, m.Ctr, cmd)
+	if err != nil {
+		return "", WrapErrorf(err, "failed to execute command: %s", command)
+	}
+
+	// The output is scanned even if the command failed, since its errors can hold sensitive values too.
+	if findings := redactor.findings(result.stdout + "\n" + result.stderr); len(findings) > 0 {
+		return "", Errorf("sensitive values detected in the plan output: %s", strings.Join(findings, ", "))
+	}
+
+	if err := result.failure(redactor, command); err != nil {
+		return "", err
+	}
+
+	return redactor.redact(result.stdout), nil
+}
+
+// runRedactedExec executes the command in the container without failing on a non-zero exit code,
+// since the error of a failed execution carries its raw stdout and stderr. The outputs aren't redacted
+// yet: the caller redacts them, and checks the failure with redactedExec.failure.
+func runRedactedExec(ctx context.Context, ctr *dagger.Container, cmd []string) (*redactedExec, error) {
+	ctr = ctr.
+		WithExec(cmd, dagger.ContainerWithExecOpts{
+			Expect: dagger.ReturnTypeAny,
+		})
+
+	stdout, err := ctr.Stdout(ctx)
+	if err != nil {
+		return nil, WrapError(err, "failed to get stdout from command")
+	}
+
+	stderr, err := ctr.Stderr(ctx)
+	if err != nil {
+		return nil, WrapError(err, "failed to get stderr from command")
+	}
+
+	exitCode, err := ctr.ExitCode(ctx)
+	if err != nil {
+		return nil, WrapError(err, "failed to get the exit code from command")
+	}
+
+	return &redactedExec{
+		ctr:      ctr,
+		stdout:   stdout,
+		stderr:   stderr,
+		exitCode: exitCode,
+	}, nil
+}
+
+// failure returns an error with the exit code and the redacted stderr if the command failed, or nil.
+func (e *redactedExec) failure(r *redactor, command string) error {
+	if e.exitCode == 0 {
+		return nil
+	}
+
+	return Errorf("command %s failed with exit code %d: %s", command, e.exitCode,
+		r.redact(strings.TrimSpace(e.stderr)))
+}
+
+// registerSecretsToRedact registers the secrets whose values are redacted from the outputs and logs.
+func (m *Terragrunt) registerSecretsToRedact(secrets []*dagger.Secret) {
+	if m.Redaction == nil {
+		m.Redaction = &RedactionConfig{}
+	}
+
+	m.Redaction.Secrets = append(m.Redaction.Secrets, secrets...)
+}
+
+// newRedactor returns a redactor with the values of the registered secrets, and the configured patterns.
+func (m *Terragrunt) newRedactor(ctx context.Context) (*redactor, error) {
+	cfg := m.Redaction
+	if cfg == nil {
+		cfg = &RedactionConfig{}
+	}
+
+	r := &redactor{}
+
+	for _, secret := range cfg.Secrets {
+		value, err := secret.Plaintext(ctx)
+		if err != nil {
+			return nil, WrapError(err, "failed to read the value of a secret to redact")
+		}
+
+		for _, candidate := range []string{value, strings.TrimSpace(value)} {
+			if len(candidate) >= minRedactedSecretLength {
+				r.values = append(r.values, candidate)
+			}
+		}
+	}
+
+	// Longer values first, so a value containing another one is fully redacted.
+	sort.Slice(r.values, func(i, j int) bool {
+		return len(r.values[i]) > len(r.values[j])
+	})
+
+	if !cfg.DisableDefaultPatterns {
+		names := make([]string, 0, len(defaultRedactionPatterns))
+		for name := range defaultRedactionPatterns {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			r.patterns = append(r.patterns, redactionPattern{
+				name:  name,
+				regex: regexp.MustCompile(defaultRedactionPatterns[name]),
+			})
+		}
+	}
+
+	for _, pattern := range cfg.Patterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, WrapErrorf(err, "invalid redaction pattern: %s", pattern)
+		}
+
+		r.patterns = append(r.patterns, redactionPattern{
+			name:  pattern,
+			regex: regex,
+		})
+	}
+
+	return r, nil
+}
+
+// redact returns the text with the secret values and the pattern matches masked.
+func (r *redactor) redact(text string) string {
+	for _, value := range r.values {
+		text = strings.ReplaceAll(text, value, redactedMask)
+	}
+
+	for _, pattern := range r.patterns {
+		text = pattern.regex.ReplaceAllString(text, redactedMask)
+	}
+
+	return text
+}
+
+// findings returns the names of the sensitive values detected in the text, without the values themselves.
+func (r *redactor) findings(text string) []string {
+	var findings []string
+
+	for _, value := range r.values {
+		if strings.Contains(text, value) {
+			findings = append(findings, secretValueFindingName)
+
+			break
+		}
+	}
+
+	for _, pattern := range r.patterns {
+		if pattern.regex.MatchString(text) {
+			findings = append(findings, pattern.name)
+		}
+	}
+
+	return findings
+}
+
+// redactFile returns a new file with the same name and the redacted contents of the given file.
+func (r *redactor) redactFile(ctx context.Context, name string, file *dagger.File) (*dagger.File, error) {
+	contents, err := file.Contents(ctx)
+	if err != nil {
+		return nil, WrapErrorf(err, "failed to read the file to redact: %s", name)
+	}
+
+	return dag.
+		Directory().
+		WithNewFile(name, r.redact(contents)).
+		File(name), nil
+}
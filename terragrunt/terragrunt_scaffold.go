@@ -0,0 +1,117 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
+	"github.com/Excoriate/daggerx/pkg/fixtures"
+)
+
+const (
+	// scaffoldTemplatesDir is the path where the boilerplate templates are mounted in the container.
+	scaffoldTemplatesDir = "/home/terragrunt/scaffold-templates"
+)
+
+// Scaffold generates a new Terragrunt unit from a module source, non-interactively.
+//
+// It runs 'terragrunt scaffold' with the given module source URL and version, passing the
+// input variables to the boilerplate templates. The generated terragrunt.hcl file (and any
+// other file produced by the templates) is returned in a directory.
+//
+// Parameters:
+// - moduleURL: The source URL of the Terraform module, e.g., "github.com/org/modules//vpc".
+// - version: The version (git ref) of the module. Optional parameter.
+// - vars: The input variables for the templates, in the form "key=value". Optional parameter.
+// - templates: The directory containing the boilerplate templates. Optional parameter.
+// - source: The directory where the unit is generated, e.g., a live repository with the root
+// terragrunt.hcl file. Optional parameter.
+// - unitPath: The path of the unit to generate, relative to the source. Optional parameter.
+// - noIncludeRoot: Whether to skip the include of the root terragrunt.hcl file. Optional parameter.
+//
+// Returns:
+// - *dagger.Directory: The directory of the generated unit.
+// - error: An error if the module URL is empty, or if an input variable is malformed.
+func (m *Terragrunt) Scaffold(
+	// moduleURL is the source URL of the Terraform module, e.g., "github.com/org/modules//vpc".
+	moduleURL string,
+	// version is the version (git ref) of the module, e.g., "v1.2.0".
+	// +optional
+	version string,
+	// vars are the input variables for the templates, in the form "key=value".
+	// +optional
+	vars []string,
+	// templates is the directory containing the boilerplate templates.
+	// +optional
+	templates *dagger.Directory,
+	// source is the directory where the unit is generated. If not set, an empty directory is used.
+	// +optional
+	source *dagger.Directory,
+	// unitPath is the path of the unit to generate, relative to the source.
+	// +optional
+	unitPath string,
+	// noIncludeRoot is whether to skip the include of the root terragrunt.hcl file.
+	// +optional
+	noIncludeRoot bool,
+) (*dagger.Directory, error) {
+	if moduleURL == "" {
+		return nil, Errorf("the module URL is required to scaffold a new unit")
+	}
+
+	cmd := []string{m.Tg.getEntrypoint(), "scaffold", withModuleRef(moduleURL, version)}
+
+	if templates != nil {
+		m.Ctr = m.Ctr.
+			WithMountedDirectory(scaffoldTemplatesDir, templates, dagger.ContainerWithMountedDirectoryOpts{
+				Owner: terragruntCtrUser,
+			})
+
+		cmd = append(cmd, scaffoldTemplatesDir)
+	}
+
+	for _, variable := range vars {
+		key, value, found := strings.Cut(variable, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, Errorf("invalid scaffold variable: %s, it should be in the form key=value", variable)
+		}
+
+		cmd = append(cmd, "--var="+strings.TrimSpace(key)+"="+value)
+	}
+
+	if noIncludeRoot {
+		cmd = append(cmd, "--terragrunt-no-include-root")
+	}
+
+	if source == nil {
+		source = dag.Directory()
+	}
+
+	unitDir := filepath.Join(fixtures.MntPrefix, unitPath)
+
+	ctr := m.
+		WithSource(source, "", terragruntCtrUser).
+		Ctr.
+		WithDirectory(unitDir, dag.Directory(), dagger.ContainerWithDirectoryOpts{
+			Owner: terragruntCtrUser,
+		}).
+		WithWorkdir(unitDir).
+		WithEnvVariable("TERRAGRUNT_NON_INTERACTIVE", "true").
+		WithExec(cmd)
+
+	return ctr.Directory(unitDir), nil
+}
+
+// withModuleRef returns the module URL with the given version set as the 'ref' query parameter.
+// If the version is empty, the module URL is returned as is.
+func withModuleRef(moduleURL, version string) string {
+	if version == "" {
+		return moduleURL
+	}
+
+	separator := "?"
+	if strings.Contains(moduleURL, "?") {
+		separator = "&"
+	}
+
+	return moduleURL + separator + "ref=" + version
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
+	"github.com/Excoriate/daggerx/pkg/fixtures"
+)
+
+const (
+	// stateOpsDir is the directory where the state files are written during a cross-unit move.
+	stateOpsDir                    = "/home/terragrunt/state-ops"
+	stateBackupFileName            = "backup.tfstate"
+	stateFileName                  = "terraform.tfstate"
+	stateSourceFileName            = "source.tfstate"
+	stateDestinationFileName       = "destination.tfstate"
+	stateDestinationBackupFileName = "destination-backup.tfstate"
+)
+
+// StateOperationResult holds the result of a mutating state operation.
+type StateOperationResult struct {
+	// Backup is the state of the unit, pulled before the operation.
+	Backup *dagger.File
+	// DestinationBackup is the state of the destination unit, pulled before a move between units.
+	// It's only set when the resource is moved to another unit.
+	DestinationBackup *dagger.File
+	// State is the state of the unit, pulled after the operation.
+	State *dagger.File
+	// DestinationState is the state of the destination unit after a move between units.
+	// It's only set when the resource is moved to another unit.
+	DestinationState *dagger.File
+	// Output is the output of the operation.
+	Output string
+}
+
+// StateList lists the resources in the state of the given unit.
+//
+// Parameters:
+// - ctx: The context to use when executing the command.
+// - source: The source directory that includes the source code.
+// - module: The module or terragrunt unit. Optional parameter.
+// - addresses: The addresses to filter the resources by. Optional parameter.
+// - envVars: The environment variables to pass to the container. Optional parameter.
+// - secrets: The secrets to pass to the container. Optional parameter.
+//
+// Returns:
+// - string: The list of resources, one per line.
+// - error: An error if the command fails.
+//
+//nolint:lll // It's okay, since the ignore pattern is included.
+func (m *Terragrunt) StateList(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
+	// +optional
+	module string,
+	// addresses are the addresses to filter the resources by.
+	// +optional
+	addresses []string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+) (string, error) {
+	return m.runStateReadCmd(ctx, append([]string{"list"}, addresses...), source, module, envVars, secrets)
+}
+
+// StateShow shows the attributes of a single resource in the state of the given unit.
+//
+// Parameters:
+// - ctx: The context to use when executing the command.
+// - address: The address of the resource to show.
+// - source: The source directory that includes the source code.
+// - module: The module or terragrunt unit. Optional parameter.
+// - envVars: The environment variables to pass to the container. Optional parameter.
+// - secrets: The secrets to pass to the container. Optional parameter.
+//
+// Returns:
+// - string: The attributes of the resource.
+// - error: An error if the address is empty, or if the command fails.
+//
+//nolint:lll // It's okay, since the ignore pattern is included.
+func (m *Terragrunt) StateShow(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// address is the address of the resource to show.
+	address string,
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
+	// +optional
+	module string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+) (string, error) {
+	if address == "" {
+		return "", Errorf("the resource address is required to show it")
+	}
+
+	return m.runStateReadCmd(ctx, []string{"show", address}, source, module, envVars, secrets)
+}
+
+// StateMv moves a resource in the state, within the same unit or to another unit.
+//
+// The state of the unit (and of the destination unit, if any) is backed up before the move.
+// When a destination unit is passed, both states are pulled, the resource is moved between the
+// local copies, and both states are pushed back to their backends. The source state is pushed
+// first, so a failed push never leaves the resource in both states. If the destination push fails,
+// the result is returned along with the error, so the resource can be restored from the backups.
+// The local move runs with the binary Terragrunt wraps: the given tool, or the one set in
+// TERRAGRUNT_TFPATH, terraform by default.
+//
+// Parameters:
+// - ctx: The context to use when executing the command.
+// - sourceAddress: The address of the resource to move.
+// - destinationAddress: The new address of the resource.
+// - source: The source directory that includes the source code.
+// - module: The module or terragrunt unit the resource belongs to. Optional parameter.
+// - destinationModule: The module or terragrunt unit to move the resource to. Optional parameter.
+// - envVars: The environment variables to pass to the container. Optional parameter.
+// - secrets: The secrets to pass to the container. Optional parameter.
+// - tool: The tool Terragrunt wraps, "terraform" or "opentofu". Optional parameter.
+//
+// Returns:
+// - *StateOperationResult: The state backups, the resulting states and the output of the move.
+// - error: An error if the addresses are empty, or if any of the commands fail.
+//
+//nolint:funlen,lll // It's okay, the move between units is sequential and easier to follow in a single function.
+func (m *Terragrunt) StateMv(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// sourceAddress is the address of the resource to move.
+	sourceAddress string,
+	// destinationAddress is the new address of the resource.
+	destinationAddress string,
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
+	// +optional
+	module string,
+	// destinationModule is the module or terragrunt unit to move the resource to.
+	// +optional
+	destinationModule string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+	// tool is the tool Terragrunt wraps, "terraform" or "opentofu". If not set, the binary set in
+	// TERRAGRUNT_TFPATH is used, or terraform if it's not set either.
+	// +optional
+	tool string,
+) (*StateOperationResult, error) {
+	if sourceAddress == "" || destinationAddress == "" {
+		return nil, Errorf("both the source and the destination addresses are required to move a resource")
+	}
+
+	if tool != "" {
+		if tool != string(TerraformTool) && tool != string(OpentofuTool) {
+			return nil, Errorf("invalid tool to move the resource: %s, it should be %s or %s",
+				tool, TerraformTool, OpentofuTool)
+		}
+
+		iacCmd, err := m.getIACCmd(tool)
+		if err != nil {
+			return nil, err
+		}
+
+		// Terragrunt pulls and pushes the states with the same binary that moves the resource.
+		m.Ctr = m.Ctr.
+			WithEnvVariable("TERRAGRUNT_TFPATH", iacCmd.getEntrypoint())
+	}
+
+	if destinationModule == "" || filepath.Clean(destinationModule) == filepath.Clean(module) {
+		return m.runStateMutatingCmd(ctx, "state", []string{"mv", sourceAddress, destinationAddress},
+			source, module, envVars, secrets)
+	}
+
+	pullCmd, err := m.prepareExec(ctx, "state", []string{"pull"}, false, source, module, envVars, secrets, "")
+	if err != nil {
+		return nil, WrapError(err, "failed to prepare the state pull command")
+	}
+
+	srcUnitDir := filepath.Join(fixtures.MntPrefix, module)
+	dstUnitDir := filepath.Join(fixtures.MntPrefix, destinationModule)
+	srcStatePath := filepath.Join(stateOpsDir, stateSourceFileName)
+	dstStatePath := filepath.Join(stateOpsDir, stateDestinationFileName)
+
+	ctr := m.withStateOpsDir().Ctr
+
+	tfPath, err := ctr.EnvVariable(ctx, "TERRAGRUNT_TFPATH")
+	if err != nil {
+		return nil, WrapError(err, "failed to resolve the binary wrapped by terragrunt")
+	}
+
+	if tfPath == "" {
+		tfPath = string(TerraformTool)
+	}
+
+	srcState, err := ctr.WithWorkdir(srcUnitDir).WithExec(pullCmd).Stdout(ctx)
+	if err != nil {
+		return nil, WrapErrorf(err, "failed to back up the state of the unit: %s", module)
+	}
+
+	dstState, err := ctr.WithWorkdir(dstUnitDir).WithExec(pullCmd).Stdout(ctx)
+	if err != nil {
+		return nil, WrapErrorf(err, "failed to back up the state of the destination unit: %s", destinationModule)
+	}
+
+	backups := dag.
+		Directory().
+		WithNewFile(stateBackupFileName, srcState).
+		WithNewFile(stateDestinationBackupFileName, dstState)
+
+	stateFileOpts := dagger.ContainerWithNewFileOpts{Owner: terragruntCtrUser}
+
+	// The move happens between the local copies of both states, outside any unit, so no
+	// backend is involved until the states are pushed.
+	movedCtr := ctr.
+		WithNewFile(srcStatePath, srcState, stateFileOpts).
+		WithNewFile(dstStatePath, dstState, stateFileOpts).
+		WithWorkdir(stateOpsDir).
+		WithExec([]string{
+			tfPath, "state", "mv",
+			"-state=" + srcStatePath,
+			"-state-out=" + dstStatePath,
+			sourceAddress, destinationAddress,
+		})
+
+	mvOut, err := movedCtr.Stdout(ctx)
+	if err != nil {
+		return nil, WrapErrorf(err, "failed to move %s to %s", sourceAddress, destinationAddress)
+	}
+
+	result := &StateOperationResult{
+		Backup:            backups.File(stateBackupFileName),
+		DestinationBackup: backups.File(stateDestinationBackupFileName),
+		State:             movedCtr.File(srcStatePath),
+		DestinationState:  movedCtr.File(dstStatePath),
+		Output:            mvOut,
+	}
+
+	entrypoint := m.Tg.getEntrypoint()
+
+	srcPushedCtr := movedCtr.
+		WithWorkdir(srcUnitDir).
+		WithExec([]string{entrypoint, "state", "push", srcStatePath})
+
+	if _, err := srcPushedCtr.Sync(ctx); err != nil {
+		return nil, WrapErrorf(err, "failed to push the state of the unit %s, no state was modified", module)
+	}
+
+	dstPushedCtr := srcPushedCtr.
+		WithWorkdir(dstUnitDir).
+		WithExec([]string{entrypoint, "state", "push", dstStatePath})
+
+	if _, err := dstPushedCtr.Sync(ctx); err != nil {
+		return result, WrapErrorf(err,
+			"failed to push the state of the destination unit %s, %s was removed from the unit %s; "+
+				"restore its state from the returned backup", destinationModule, sourceAddress, module)
+	}
+
+	return result, nil
+}
+
+// StateRm removes resources from the state of the given unit, after backing it up.
+//
+// Parameters:
+// - ctx: The context to use when executing the command.
+// - addresses: The addresses of the resources to remove.
+// - source: The source directory that includes the source code.
+// - module: The module or terragrunt unit. Optional parameter.
+// - envVars: The environment variables to pass to the container. Optional parameter.
+// - secrets: The secrets to pass to the container. Optional parameter.
+//
+// Returns:
+// - *StateOperationResult: The state backup, the resulting state and the output of the removal.
+// - error: An error if no address is passed, or if any of the commands fail.
+//
+//nolint:lll // It's okay, since the ignore pattern is included.
+func (m *Terragrunt) StateRm(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// addresses are the addresses of the resources to remove.
+	addresses []string,
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
+	// +optional
+	module string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+) (*StateOperationResult, error) {
+	if len(addresses) == 0 {
+		return nil, Errorf("at least one resource address is required to remove it from the state")
+	}
+
+	return m.runStateMutatingCmd(ctx, "state", append([]string{"rm"}, addresses...), source, module, envVars, secrets)
+}
+
+// Import imports an existing resource into the state of the given unit, after backing it up.
+//
+// Parameters:
+// - ctx: The context to use when executing the command.
+// - address: The address to import the resource to.
+// - id: The provider-specific ID of the resource to import.
+// - source: The source directory that includes the source code.
+// - module: The module or terragrunt unit. Optional parameter.
+// - envVars: The environment variables to pass to the container. Optional parameter.
+// - secrets: The secrets to pass to the container. Optional parameter.
+//
+// Returns:
+// - *StateOperationResult: The state backup, the resulting state and the output of the import.
+// - error: An error if the address or the ID are empty, or if any of the commands fail.
+//
+//nolint:lll // It's okay, since the ignore pattern is included.
+func (m *Terragrunt) Import(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// address is the address to import the resource to.
+	address string,
+	// id is the provider-specific ID of the resource to import.
+	id string,
+	// source is the source directory that includes the source code.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// module is the module to execute or the terragrunt configuration where the terragrunt.hcl file is located.
+	// +optional
+	module string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+) (*StateOperationResult, error) {
+	if address == "" || id == "" {
+		return nil, Errorf("both the resource address and the resource ID are required to import a resource")
+	}
+
+	return m.runStateMutatingCmd(ctx, "import", []string{address, id}, source, module, envVars, secrets)
+}
+
+// runStateReadCmd runs a read-only 'state' subcommand for the given unit, and returns its output.
+func (m *Terragrunt) runStateReadCmd(
+	ctx context.Context,
+	args []string,
+	source *dagger.Directory,
+	module string,
+	envVars []string,
+	secrets []*dagger.Secret,
+) (string, error) {
+	cmd, err := m.prepareExec(ctx, "state", args, false, source, module, envVars, secrets, "")
+	if err != nil {
+		return "", WrapError(err, "failed to prepare the state command")
+	}
+
+	out, err := m.Ctr.
+		WithEnvVariable("TERRAGRUNT_FORWARD_TF_STDOUT", "true").
+		WithExec(cmd).
+		Stdout(ctx)
+
+	if err != nil {
+		return "", WrapErrorf(err, "failed to run the state command: %s", strings.Join(args, " "))
+	}
+
+	return out, nil
+}
+
+// runStateMutatingCmd backs up the state of the given unit, runs the mutating command, and pulls the
+// resulting state.
+// The backup is pulled (and evaluated) before the command runs, so it's always the state prior
+// to the operation.
+func (m *Terragrunt) runStateMutatingCmd(
+	ctx context.Context,
+	command string,
+	args []string,
+	source *dagger.Directory,
+	module string,
+	envVars []string,
+	secrets []*dagger.Secret,
+) (*StateOperationResult, error) {
+	cmd, err := m.prepareExec(ctx, command, args, false, source, module, envVars, secrets, "")
+	if err != nil {
+		return nil, WrapErrorf(err, "failed to prepare the command: %s", command)
+	}
+
+	pulledCtr := m.withStateOpsDir().
+		Ctr.
+		WithExec([]string{m.Tg.getEntrypoint(), "state", "pull"})
+
+	state, err := pulledCtr.Stdout(ctx)
+	if err != nil {
+		return nil, WrapError(err, "failed to back up the state before the operation")
+	}
+
+	cmdCtr := pulledCtr.WithExec(cmd)
+
+	out, err := cmdCtr.Stdout(ctx)
+	if err != nil {
+		return nil, WrapErrorf(err, "failed to run the command: %s %s, restore the state from the backup",
+			command, strings.Join(args, " "))
+	}
+
+	newState, err := cmdCtr.
+		WithExec([]string{m.Tg.getEntrypoint(), "state", "pull"}).
+		Stdout(ctx)
+
+	if err != nil {
+		return nil, WrapError(err, "failed to pull the state after the operation")
+	}
+
+	states := dag.
+		Directory().
+		WithNewFile(stateBackupFileName, state).
+		WithNewFile(stateFileName, newState)
+
+	return &StateOperationResult{
+		Backup: states.File(stateBackupFileName),
+		State:  states.File(stateFileName),
+		Output: out,
+	}, nil
+}
+
+// withStateOpsDir creates the state operations directory in the container, owned by the terragrunt
+// user, and forwards the terraform stdout so the pulled states are returned as is.
+func (m *Terragrunt) withStateOpsDir() *Terragrunt {
+	m.Ctr = m.Ctr.
+		WithDirectory(stateOpsDir, dag.Directory(), dagger.ContainerWithDirectoryOpts{
+			Owner: terragruntCtrUser,
+		}).
+		WithEnvVariable("TERRAGRUNT_FORWARD_TF_STDOUT", "true")
+
+	return m
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
+	"github.com/Excoriate/daggerx/pkg/fixtures"
+)
+
+const (
+	// tfTestFileSuffix is the suffix of the Terraform/OpenTofu test files.
+	tfTestFileSuffix = ".tftest.hcl"
+	// defaultTfTestDirectory is the default directory, relative to the module, where the test files are located.
+	defaultTfTestDirectory = "tests"
+	tfTestLogFileName      = "test.log"
+	tfTestJUnitFileName    = "junit.xml"
+	tfTestReportFileName   = "test-report.json"
+	// tfTestJUnitDir is the directory where the JUnit XML reports are written in the container.
+	tfTestJUnitDir   = "/home/terragrunt/test-reports"
+	tfTestStatusPass = "pass"
+	tfTestStatusFail = "fail"
+	tfTestJUnitFlag  = "-junit-xml"
+)
+
+// tfTestSummaryRegex matches the summary line printed at the end of 'terraform test', e.g.,
+// "Success! 2 passed, 0 failed." or "Failure! 1 passed, 1 failed, 1 skipped.".
+var tfTestSummaryRegex = regexp.MustCompile(`(?m)(Success|Failure)! (\d+) passed, (\d+) failed(?:, (\d+) skipped)?`)
+
+// ModuleTestReport represents the result of running the tests of a single module.
+type ModuleTestReport struct {
+	// Module is the path of the module, relative to the source directory.
+	Module string
+	// Status is the status of the tests: "pass" or "fail".
+	Status string
+	// Passed is the number of test runs that passed.
+	Passed int
+	// Failed is the number of test runs that failed.
+	Failed int
+	// Skipped is the number of test runs that were skipped.
+	Skipped int
+	// ExitCode is the exit code of the test command.
+	ExitCode int
+	// JUnitXML is whether a JUnit XML report was generated for the module.
+	JUnitXML bool
+}
+
+// TestModules runs 'terraform test' (or 'tofu test') for the modules of the source directory.
+//
+// If no module is passed, the modules are discovered from the *.tftest.hcl files of the source directory,
+// either at the root of the module or in its test directory. Each module is initialized without a backend
+// before running the tests. A module that fails to initialize is reported as failed, and the other
+// modules are still tested.
+//
+// The returned directory contains, for each module, a directory with the same relative path, containing:
+// - test.log: The output of the test command, or of 'init' if the module failed to initialize.
+// - exit-code: The exit code of the test command, or of 'init' if the module failed to initialize.
+// - junit.xml: The JUnit XML report, only if the tool supports it (Terraform 1.11 and later).
+// And a test-report.json file with the parsed summary of every module.
+//
+// Parameters:
+// - ctx: The context to use when executing the command.
+// - source: The source directory that includes the modules.
+// - modules: The modules to test, relative to the source directory. Optional parameter.
+// - tool: The tool to run the tests with, "terraform" (default) or "opentofu". Optional parameter.
+// - varFiles: The variable files to pass to the test command, relative to each module. Optional parameter.
+// - vars: The variables to pass to the test command, in the form "key=value". Optional parameter.
+// - filters: The test files to run, relative to each module. Optional parameter.
+// - testDirectory: The test directory of the modules. Default is "tests". Optional parameter.
+// - envVars: The environment variables to pass to the container. Optional parameter.
+// - secrets: The secrets to pass to the container. Optional parameter.
+// - failOnTestFailure: Whether to return an error if the tests of any module fail. Optional parameter.
+//
+// Returns:
+// - *dagger.Directory: The directory with the test logs and reports of each module.
+// - error: An error if no module is found, or if the tests fail and failOnTestFailure is set.
+//
+//nolint:funlen,lll // It's okay, the workflow is sequential and the ignore pattern is included.
+func (m *Terragrunt) TestModules(
+	// ctx is the context to use when executing the command.
+	// +optional
+	ctx context.Context,
+	// source is the source directory that includes the modules.
+	// +defaultPath="/"
+	// +ignore=[".terragrunt-cache", ".terraform", ".github", ".gitignore", ".git", "vendor", "node_modules", "build", "dist", "log"]
+	source *dagger.Directory,
+	// modules are the modules to test, relative to the source directory. If not set, they're discovered.
+	// +optional
+	modules []string,
+	// tool is the tool to run the tests with, "terraform" or "opentofu". Default is "terraform".
+	// +optional
+	tool string,
+	// varFiles are the variable files to pass to the test command, relative to each module.
+	// +optional
+	varFiles []string,
+	// vars are the variables to pass to the test command, in the form "key=value".
+	// +optional
+	vars []string,
+	// filters are the test files to run, relative to each module, e.g., "tests/defaults.tftest.hcl".
+	// +optional
+	filters []string,
+	// testDirectory is the test directory of the modules. Default is "tests".
+	// +optional
+	testDirectory string,
+	// envVars is the environment variables to pass to the container.
+	// +optional
+	envVars []string,
+	// secrets is the secrets to pass to the container.
+	// +optional
+	secrets []*dagger.Secret,
+	// failOnTestFailure is whether to return an error if the tests of any module fail.
+	// +optional
+	failOnTestFailure bool,
+) (*dagger.Directory, error) {
+	if tool == "" {
+		tool = string(TerraformTool)
+	}
+
+	if tool != string(TerraformTool) && tool != string(OpentofuTool) {
+		return nil, Errorf("invalid tool to run the tests: %s, it should be %s or %s", tool, TerraformTool, OpentofuTool)
+	}
+
+	if testDirectory == "" {
+		testDirectory = defaultTfTestDirectory
+	}
+
+	if len(modules) == 0 {
+		discovered, err := discoverTestedModules(ctx, source, testDirectory)
+		if err != nil {
+			return nil, err
+		}
+
+		modules = discovered
+	}
+
+	if len(modules) == 0 {
+		return nil, Errorf("no module with %s files found in the source directory", tfTestFileSuffix)
+	}
+
+	testArgs, err := newTfTestArgs(varFiles, vars, filters, testDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	testCmd, err := m.prepareExec(ctx, "test", testArgs, false, source, "", envVars, secrets, tool)
+	if err != nil {
+		return nil, WrapError(err, "failed to prepare the test command")
+	}
+
+	baseCtr := m.Ctr.
+		WithDirectory(tfTestJUnitDir, dag.Directory(), dagger.ContainerWithDirectoryOpts{
+			Owner: terragruntCtrUser,
+		})
+
+	supportsJUnit, err := toolSupportsJUnitReports(ctx, baseCtr, tool)
+	if err != nil {
+		return nil, err
+	}
+
+	redactor, err := m.newRedactor(ctx)
+	if err != nil {
+		return nil, WrapError(err, "failed to configure the redaction of the test logs")
+	}
+
+	var (
+		reports   []ModuleTestReport
+		failed    []string
+		reportDir = dag.Directory()
+	)
+
+	for _, module := range modules {
+		junitPath := filepath.Join(tfTestJUnitDir, strings.ReplaceAll(module, "/", "_")+".xml")
+
+		moduleCmd := testCmd
+		if supportsJUnit {
+			moduleCmd = append(append([]string{}, testCmd...), tfTestJUnitFlag+"="+junitPath)
+		}
+
+		initialized, err := runRedactedExec(ctx, baseCtr.
+			WithWorkdir(filepath.Join(fixtures.MntPrefix, module)),
+			[]string{tool, "init", "-backend=false", "-input=false", "-no-color"})
+		if err != nil {
+			return nil, WrapErrorf(err, "failed to initialize module: %s", module)
+		}
+
+		// A module that fails to initialize is reported as failed, with the output of init as its log,
+		// so the other modules are still tested.
+		if initialized.exitCode != 0 {
+			report := parseTfTestSummary(module, "", initialized.exitCode)
+
+			reportDir = reportDir.WithDirectory(module, dag.
+				Directory().
+				WithNewFile(tfTestLogFileName, redactor.redact(initialized.stdout+initialized.stderr)).
+				WithNewFile(exitCodeFileName, strconv.Itoa(initialized.exitCode)))
+			reports = append(reports, report)
+			failed = append(failed, module)
+
+			continue
+		}
+
+		tested, err := runRedactedExec(ctx, initialized.ctr, moduleCmd)
+		if err != nil {
+			return nil, WrapErrorf(err, "failed to run the tests of module: %s", module)
+		}
+
+		report := parseTfTestSummary(module, tested.stdout, tested.exitCode)
+		report.JUnitXML = supportsJUnit
+
+		moduleDir := dag.
+			Directory().
+			WithNewFile(tfTestLogFileName, redactor.redact(tested.stdout+tested.stderr)).
+			WithNewFile(exitCodeFileName, strconv.Itoa(tested.exitCode))
+
+		if supportsJUnit {
+			moduleDir = moduleDir.
+				WithFile(tfTestJUnitFileName, tested.ctr.File(junitPath))
+		}
+
+		reportDir = reportDir.WithDirectory(module, moduleDir)
+		reports = append(reports, report)
+
+		if report.Status == tfTestStatusFail {
+			failed = append(failed, module)
+		}
+	}
+
+	reportAsJSON, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return nil, WrapError(err, "failed to marshal the test report")
+	}
+
+	if failOnTestFailure && len(failed) > 0 {
+		return nil, Errorf("the tests failed in %d module(s): %s", len(failed), strings.Join(failed, ", "))
+	}
+
+	return reportDir.
+		WithNewFile(tfTestReportFileName, string(reportAsJSON)), nil
+}
+
+// discoverTestedModules returns the modules of the source directory that have test files, either at
+// the root of the module or in its test directory.
+func discoverTestedModules(ctx context.Context, source *dagger.Directory, testDirectory string) ([]string, error) {
+	if source == nil {
+		return nil, Errorf("source is required, can't discover the modules without source")
+	}
+
+	testFiles, err := source.Glob(ctx, "**/*"+tfTestFileSuffix)
+	if err != nil {
+		return nil, WrapError(err, "failed to find the test files in the source directory")
+	}
+
+	seen := map[string]bool{}
+
+	var modules []string
+
+	for _, testFile := range testFiles {
+		if strings.Contains(testFile, ".terraform/") || strings.Contains(testFile, ".terragrunt-cache/") {
+			continue
+		}
+
+		module := filepath.Dir(testFile)
+		if filepath.Base(module) == filepath.Base(testDirectory) {
+			module = filepath.Dir(module)
+		}
+
+		if !seen[module] {
+			seen[module] = true
+
+			modules = append(modules, module)
+		}
+	}
+
+	sort.Strings(modules)
+
+	return modules, nil
+}
+
+// newTfTestArgs returns the arguments of the test command for the given variable files, variables and filters.
+func newTfTestArgs(varFiles, vars, filters []string, testDirectory string) ([]string, error) {
+	args := []string{"-no-color", "-test-directory=" + testDirectory}
+
+	for _, varFile := range varFiles {
+		args = append(args, "-var-file="+varFile)
+	}
+
+	for _, variable := range vars {
+		key, _, found := strings.Cut(variable, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, Errorf("invalid test variable: %s, it should be in the form key=value", variable)
+		}
+
+		args = append(args, "-var="+variable)
+	}
+
+	for _, filter := range filters {
+		args = append(args, "-filter="+filter)
+	}
+
+	return args, nil
+}
+
+// toolSupportsJUnitReports returns true if the test command of the tool supports the -junit-xml flag.
+func toolSupportsJUnitReports(ctx context.Context, ctr *dagger.Container, tool string) (bool, error) {
+	helpCtr := ctr.
+		WithExec([]string{tool, "test", "-help"}, dagger.ContainerWithExecOpts{
+			Expect: dagger.ReturnTypeAny,
+		})
+
+	stdout, err := helpCtr.Stdout(ctx)
+	if err != nil {
+		return false, WrapErrorf(err, "failed to get the help of the %s test command", tool)
+	}
+
+	stderr, err := helpCtr.Stderr(ctx)
+	if err != nil {
+		return false, WrapErrorf(err, "failed to get the help of the %s test command", tool)
+	}
+
+	return strings.Contains(stdout+stderr, tfTestJUnitFlag), nil
+}
+
+// parseTfTestSummary parses the summary line of the test output of a module. If the summary can't be
+// found (e.g., the module failed to initialize), the status is derived from the exit code.
+func parseTfTestSummary(module, output string, exitCode int) ModuleTestReport {
+	report := ModuleTestReport{
+		Module:   module,
+		Status:   tfTestStatusPass,
+		ExitCode: exitCode,
+	}
+
+	if exitCode != 0 {
+		report.Status = tfTestStatusFail
+	}
+
+	matches := tfTestSummaryRegex.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return report
+	}
+
+	summary := matches[len(matches)-1]
+	report.Passed, _ = strconv.Atoi(summary[2])
+	report.Failed, _ = strconv.Atoi(summary[3])
+	report.Skipped, _ = strconv.Atoi(summary[4])
+
+	if summary[1] == "Failure" || report.Failed > 0 {
+		report.Status = tfTestStatusFail
+	}
+
+	return report
+}
@@ -47,8 +47,6 @@ func validateTerragruntCommands(command string) error {
 // The log level must be one of the valid
 // log levels such as "TRACE", "DEBUG", "INFO", "WARN", "ERROR", or "JSON".
 // Returns an error if the log level is invalid or empty.
-//
-//nolint:unused // The log level is not used in the function, but it is validated.
 func validateTerraformLogLevel(logLevel string) error {
 	if logLevel == "" {
 		return WrapError(nil, "log level is required, can't validate empty log level")
@@ -88,7 +86,7 @@ func validateTerragruntLogLevel(logLevel string) error {
 }
 
 // validateMainTerraformCommands validates the provided terraform main command.
-// The command must be one of the valid main commands such as "init", "validate", "plan", "apply", or "destroy".
+// The command must be one of the valid main commands such as "init", "validate", "plan", "apply", "destroy", or "test".
 // Returns an error if the command is invalid or empty.
 func validateMainTerraformCommands(command string) error {
 	if command == "" {
@@ -96,7 +94,7 @@ func validateMainTerraformCommands(command string) error {
 	}
 
 	validMainCommands := map[string]bool{
-		"init": true, "validate": true, "plan": true, "apply": true, "destroy": true,
+		"init": true, "validate": true, "plan": true, "apply": true, "destroy": true, "test": true,
 	}
 
 	if !validMainCommands[command] {
@@ -129,3 +127,18 @@ func validateTerraformOtherCommands(command string) error {
 
 	return nil
 }
+
+// validatePlainIACCommands validates the provided command for a plain Terraform or OpenTofu project,
+// without Terragrunt. The command must be one of the main or the other Terraform/OpenTofu commands.
+// Returns an error if the command is invalid or empty.
+func validatePlainIACCommands(tool, command string) error {
+	if command == "" {
+		return WrapError(nil, "command is required, can't validate empty command")
+	}
+
+	if validateMainTerraformCommands(command) != nil && validateTerraformOtherCommands(command) != nil {
+		return WrapErrorf(nil, "invalid %s command: %s, terragrunt commands aren't supported", tool, command)
+	}
+
+	return nil
+}
@@ -267,3 +267,95 @@ func (m *Tests) TestContainerBaseApkoWithCustomVersions(ctx context.Context) err
 
 	return nil
 }
+
+// TestContainerBaseApkoWolfi tests the initialization of a target module with the embedded Wolfi
+// APKO preset, and verifies that the IaC tools are installed and that the SBOM is generated.
+//
+// Parameters:
+// - ctx: The context for controlling the execution of the function.
+//
+// Returns:
+// - An error if any of the steps fail, otherwise nil.
+func (m *Tests) TestContainerBaseApkoWolfi(ctx context.Context) error {
+	targetModule := dag.Terragrunt(dagger.TerragruntOpts{
+		ApkoDistro: "wolfi",
+	})
+
+	targetCtr := targetModule.Ctr()
+
+	osReleaseOut, osReleaseErr := targetCtr.
+		WithExec([]string{"cat", "/etc/os-release"}).
+		Stdout(ctx)
+
+	if osReleaseErr != nil {
+		return WrapError(osReleaseErr, "failed to read /etc/os-release")
+	}
+
+	if !strings.Contains(strings.ToLower(osReleaseOut), "wolfi") {
+		return Errorf("expected the base image to be Wolfi, got %s", osReleaseOut)
+	}
+
+	if err := checkTerragruntInstalled(ctx, targetCtr); err != nil {
+		return err
+	}
+
+	if err := checkTerraformInstalled(ctx, targetCtr); err != nil {
+		return err
+	}
+
+	sbomEntries, sbomErr := targetModule.
+		ApkoSbom().
+		Entries(ctx)
+
+	if sbomErr != nil {
+		return WrapError(sbomErr, "failed to list the SBOM directory")
+	}
+
+	if len(sbomEntries) == 0 {
+		return Errorf("expected the SBOM directory to contain at least one file, got none")
+	}
+
+	return nil
+}
+
+// TestContainerBaseApkoWithVersionFiles tests the initialization of a target module with the IaC tools
+// versions resolved from the version files of a source directory.
+//
+// The testdata directory pins Terragrunt in both .terragrunt-version and .tool-versions (the dedicated file
+// wins), and OpenTofu in .tool-versions. Terraform is passed explicitly, which takes precedence over the files.
+//
+// Parameters:
+// - ctx: The context for controlling the execution of the function.
+//
+// Returns:
+// - An error if any of the steps fail, otherwise nil.
+func (m *Tests) TestContainerBaseApkoWithVersionFiles(ctx context.Context) error {
+	targetModule := dag.Terragrunt(dagger.TerragruntOpts{
+		TfVersion:          "1.4.6",
+		VersionFilesSource: m.getTestDir("").Directory("terragrunt-tool-versions"),
+	})
+
+	targetCtr := targetModule.Ctr()
+
+	if err := m.assertVersionOfBinaryInContainer(ctx, targetCtr, "terragrunt", "v0.66.0", ""); err != nil {
+		return err
+	}
+
+	if err := m.assertVersionOfBinaryInContainer(ctx, targetCtr, "terraform", "v1.4.6", ""); err != nil {
+		return err
+	}
+
+	if err := m.assertVersionOfBinaryInContainer(ctx, targetCtr, "opentofu", "v1.6.3", ""); err != nil {
+		return err
+	}
+
+	// The version files can't pin the tools of a provided image, so the combination is rejected.
+	if _, err := dag.Terragrunt(dagger.TerragruntOpts{
+		ImageURL:           "ghcr.io/devops-infra/docker-terragrunt:tf-1.9.5-ot-1.8.2-tg-0.67.4",
+		VersionFilesSource: m.getTestDir("").Directory("terragrunt-tool-versions"),
+	}).Ctr().ID(ctx); err == nil {
+		return Errorf("expected the version files source to be rejected along with an image URL")
+	}
+
+	return nil
+}
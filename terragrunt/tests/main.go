@@ -65,6 +65,8 @@ func (m *Tests) TestAll(ctx context.Context) error {
 	polTests.Go(m.TestContainerBaseWithPassedImage)
 	polTests.Go(m.TestContainerBaseWithAWSClI)
 	polTests.Go(m.TestContainerBaseApkoWithCustomVersions)
+	polTests.Go(m.TestContainerBaseApkoWolfi)
+	polTests.Go(m.TestContainerBaseApkoWithVersionFiles)
 	polTests.Go(m.TestTerragruntContainerIsUp)
 	polTests.Go(m.TestTerragruntBinariesAreInstalled)
 	polTests.Go(m.TestTerragruntExecInitSimpleCommand)
@@ -74,7 +76,23 @@ func (m *Tests) TestAll(ctx context.Context) error {
 	polTests.Go(m.TestTerragruntExecWithPlanOutput)
 	polTests.Go(m.TestTerragruntWithCustomRegistriesToCacheProvidersFrom)
 	polTests.Go(m.TestTerragruntWithProviderCacheServerDisabled)
+	polTests.Go(m.TestTerragruntProvidersMirror)
+	polTests.Go(m.TestTerragruntWithCloudCredentials)
+	polTests.Go(m.TestTerragruntExecWithLogs)
+	polTests.Go(m.TestTerragruntScaffold)
+	polTests.Go(m.TestTerragruntApplyReviewedPlan)
+	polTests.Go(m.TestTerragruntStateOperationsRequireAddresses)
+	polTests.Go(m.TestTerragruntStateOperations)
+	polTests.Go(m.TestTerragruntAuditModuleVersions)
+	polTests.Go(m.TestTerragruntRunAllParallel)
+	polTests.Go(m.TestTerragruntRedactionAndScanPlan)
+	polTests.Go(m.TestTerragruntRedactionOfFailedCommands)
+	polTests.Go(m.TestTerragruntTestModules)
+	polTests.Go(m.TestTerragruntTestModulesWithUninitializedModule)
+	polTests.Go(m.TestTerragruntProvidersLock)
 	polTests.Go(m.TestTfExecInitSimpleCommand)
+	polTests.Go(m.TestTfCmdExecPlainModule)
+	polTests.Go(m.TestOpentofuCmdExecPlainModule)
 
 	if err := polTests.Wait(); err != nil {
 		return WrapError(err, "there are some failed tests")
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"strings"
 
 	"github.com/Excoriate/daggerverse/terragrunt/tests/internal/dagger"
 )
@@ -67,3 +68,81 @@ func (m *Tests) TestTfExecInitSimpleCommand(ctx context.Context) error {
 
 	return nil
 }
+
+// TestTfCmdExecPlainModule tests the execution of Terraform commands in a plain Terraform module,
+// without terragrunt.hcl files.
+//
+// This function initializes the module with the Terraform command runner, plans it with a variable,
+// and validates that the Terragrunt-only commands are rejected.
+//
+// Parameters:
+// - ctx: The context for controlling the execution.
+//
+// Returns:
+// - error: If any step fails, an error is returned.
+func (m *Tests) TestTfCmdExecPlainModule(ctx context.Context) error {
+	tfCmd := dag.
+		Terragrunt().
+		WithTerragruntPermissionsOnDirsDefault().
+		Terraform().
+		WithOptions(dagger.TerragruntTerraformCmdWithOptionsOpts{
+			InAutomation: true,
+			DisableInput: true,
+		})
+
+	planOut, planErr := tfCmd.
+		Exec("init", dagger.TerragruntTerraformCmdExecOpts{
+			Source: m.getTestDir("").Directory("terraform-tests"),
+			Module: "modules/greeting",
+		}).
+		WithExec([]string{"terraform", "plan", "-no-color", "-var=name=dagger"}).
+		Stdout(ctx)
+
+	if planErr != nil {
+		return WrapError(planErr, "failed to plan the plain terraform module")
+	}
+
+	if !strings.Contains(planOut, "Hello, dagger!") {
+		return Errorf("expected the plan output to include the greeting message, got %s", planOut)
+	}
+
+	_, runAllErr := tfCmd.
+		ExecCmd(ctx, "run-all", dagger.TerragruntTerraformCmdExecCmdOpts{
+			Source: m.getTestDir("").Directory("terraform-tests"),
+		})
+
+	if runAllErr == nil {
+		return Errorf("expected the terragrunt command 'run-all' to be rejected by the terraform command runner")
+	}
+
+	return nil
+}
+
+// TestOpentofuCmdExecPlainModule tests the execution of OpenTofu commands in a plain OpenTofu module,
+// with the opentofu binary as the entrypoint.
+//
+// Parameters:
+// - ctx: The context for controlling the execution.
+//
+// Returns:
+// - error: If any step fails, an error is returned.
+func (m *Tests) TestOpentofuCmdExecPlainModule(ctx context.Context) error {
+	versionOut, versionErr := dag.
+		Terragrunt().
+		WithTerragruntPermissionsOnDirsDefault().
+		Opentofu().
+		ExecCmd(ctx, "version", dagger.TerragruntOpentofuCmdExecCmdOpts{
+			Source: m.getTestDir("").Directory("terraform-tests"),
+			Module: "modules/greeting",
+		})
+
+	if versionErr != nil {
+		return WrapError(versionErr, "failed to execute the opentofu version command")
+	}
+
+	if !strings.Contains(versionOut, "OpenTofu") {
+		return Errorf("expected the version output to come from OpenTofu, got %s", versionOut)
+	}
+
+	return nil
+}
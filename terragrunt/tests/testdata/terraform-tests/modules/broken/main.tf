@@ -0,0 +1,3 @@
+# The module has no test files, and its configuration is invalid, so it fails to initialize.
+variable "name" {
+  type = string
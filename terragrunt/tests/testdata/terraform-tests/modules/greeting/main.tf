@@ -0,0 +1,20 @@
+variable "name" {
+  type        = string
+  description = "The name to greet."
+
+  validation {
+    condition     = length(var.name) > 0
+    error_message = "The name can't be empty."
+  }
+}
+
+variable "greeting" {
+  type        = string
+  default     = "Hello"
+  description = "The greeting to use."
+}
+
+output "message" {
+  value       = "${var.greeting}, ${var.name}!"
+  description = "The greeting message."
+}
@@ -0,0 +1,25 @@
+variables {
+  name = "terragrunt"
+}
+
+run "default_greeting" {
+  command = plan
+
+  assert {
+    condition     = output.message == "Hello, terragrunt!"
+    error_message = "The default greeting message is wrong."
+  }
+}
+
+run "custom_greeting" {
+  command = plan
+
+  variables {
+    greeting = "Hola"
+  }
+
+  assert {
+    condition     = output.message == "Hola, terragrunt!"
+    error_message = "The custom greeting message is wrong."
+  }
+}
@@ -0,0 +1,3 @@
+terraform {
+  source = "../../terragrunt/modules/random-string"
+}
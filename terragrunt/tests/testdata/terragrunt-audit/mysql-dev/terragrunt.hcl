@@ -0,0 +1,3 @@
+terraform {
+  source = "github.com/gruntwork-io/terragrunt-infrastructure-modules-example//modules/mysql"
+}
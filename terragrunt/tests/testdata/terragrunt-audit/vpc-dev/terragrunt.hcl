@@ -0,0 +1,3 @@
+terraform {
+  source = "git::https://github.com/terraform-aws-modules/terraform-aws-vpc.git?ref=main"
+}
@@ -0,0 +1,16 @@
+terraform {
+  source = "../modules//echo"
+}
+
+dependency "network" {
+  config_path = "../network"
+
+  mock_outputs = {
+    name = "network-mock"
+  }
+  mock_outputs_allowed_terraform_commands = ["init", "validate", "plan"]
+}
+
+inputs = {
+  name = "app-on-${dependency.network.outputs.name}"
+}
@@ -0,0 +1,9 @@
+variable "name" {
+  type        = string
+  description = "The name to echo as an output."
+}
+
+output "name" {
+  value       = var.name
+  description = "The echoed name."
+}
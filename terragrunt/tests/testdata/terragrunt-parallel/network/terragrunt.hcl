@@ -0,0 +1,7 @@
+terraform {
+  source = "../modules//echo"
+}
+
+inputs = {
+  name = "network"
+}
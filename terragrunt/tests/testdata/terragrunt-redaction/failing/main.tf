@@ -0,0 +1,9 @@
+variable "token" {
+  type        = string
+  description = "A value that is printed in the error of the plan."
+}
+
+output "token_file" {
+  value       = file(var.token)
+  description = "Reads a file named after the token, which doesn't exist, so the plan fails and prints the token."
+}
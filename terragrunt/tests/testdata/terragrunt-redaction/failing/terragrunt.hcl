@@ -0,0 +1 @@
+# The unit fails to plan, with an error that prints its input, set through TF_VAR_token.
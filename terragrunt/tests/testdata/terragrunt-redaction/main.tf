@@ -0,0 +1,9 @@
+variable "token" {
+  type        = string
+  description = "A value that is printed in the plan output."
+}
+
+output "token" {
+  value       = var.token
+  description = "The token, deliberately not marked as sensitive."
+}
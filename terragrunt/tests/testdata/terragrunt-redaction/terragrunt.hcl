@@ -0,0 +1 @@
+# The unit uses the Terraform configuration in this directory, whose input is set through TF_VAR_token.
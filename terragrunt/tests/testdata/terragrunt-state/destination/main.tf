@@ -0,0 +1,9 @@
+resource "random_string" "moved" {
+  length  = 8
+  special = false
+}
+
+resource "random_string" "imported" {
+  length  = 8
+  special = false
+}
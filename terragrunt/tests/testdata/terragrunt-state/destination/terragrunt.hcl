@@ -0,0 +1 @@
+# The unit uses the Terraform configuration in this directory, and its seeded local state.
@@ -0,0 +1,4 @@
+resource "random_string" "this" {
+  length  = 8
+  special = false
+}
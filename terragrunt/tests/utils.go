@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"strings"
 
 	"github.com/Excoriate/daggerverse/terragrunt/tests/internal/dagger"
@@ -189,3 +190,78 @@ func (m *Tests) assertFileContentShouldContain(
 
 	return nil
 }
+
+// assertFileInDirectoryContains checks if the specified file in the directory contains the given content.
+func (m *Tests) assertFileInDirectoryContains(
+	ctx context.Context,
+	dir *dagger.Directory,
+	file string,
+	content string,
+) error {
+	fileOut, fileOutErr := dir.
+		File(file).
+		Contents(ctx)
+
+	if fileOutErr != nil {
+		return WrapErrorf(fileOutErr, "failed to get file '%s' content", file)
+	}
+
+	if !strings.Contains(fileOut, content) {
+		return Errorf("file '%s' content is expected to contain '%s', but its current content is '%s'",
+			file, content, fileOut)
+	}
+
+	return nil
+}
+
+// assertStateResource checks that the resource at the given address is in the state file, with the
+// expected 'result' attribute. If the expected result is empty, the resource must not be in the state.
+func (m *Tests) assertStateResource(
+	ctx context.Context,
+	state *dagger.File,
+	address string,
+	expectedResult string,
+) error {
+	stateOut, stateOutErr := state.Contents(ctx)
+	if stateOutErr != nil {
+		return WrapErrorf(stateOutErr, "failed to get the state content")
+	}
+
+	var parsed struct {
+		Resources []struct {
+			Type      string `json:"type"`
+			Name      string `json:"name"`
+			Instances []struct {
+				Attributes map[string]any `json:"attributes"`
+			} `json:"instances"`
+		} `json:"resources"`
+	}
+
+	if err := json.Unmarshal([]byte(stateOut), &parsed); err != nil {
+		return WrapErrorf(err, "failed to parse the state: %s", stateOut)
+	}
+
+	for _, resource := range parsed.Resources {
+		if resource.Type+"."+resource.Name != address {
+			continue
+		}
+
+		if expectedResult == "" {
+			return Errorf("expected %s not to be in the state, but it is", address)
+		}
+
+		for _, instance := range resource.Instances {
+			if instance.Attributes["result"] == expectedResult {
+				return nil
+			}
+		}
+
+		return Errorf("expected %s to have the result '%s' in the state: %s", address, expectedResult, stateOut)
+	}
+
+	if expectedResult != "" {
+		return Errorf("expected %s to be in the state: %s", address, stateOut)
+	}
+
+	return nil
+}
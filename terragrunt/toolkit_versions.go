@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/Excoriate/daggerverse/terragrunt/internal/dagger"
+)
+
+// Version files read from the source directory to resolve the versions of the IaC tools.
+const (
+	terraformVersionFile  = ".terraform-version"
+	terragruntVersionFile = ".terragrunt-version"
+	openTofuVersionFile   = ".opentofu-version"
+	toolVersionsFile      = ".tool-versions"
+)
+
+// pinnedVersionRegex matches a concrete version, with or without the 'v' prefix, e.g., "1.9.7" or "v0.68.1".
+var pinnedVersionRegex = regexp.MustCompile(`^v?\d+\.\d+\.\d+([-+][0-9A-Za-z.\-+]+)?$`)
+
+// toolVersionsPluginNames maps the asdf/mise plugin names of the .tool-versions file to the IaC tools.
+//
+//nolint:gochecknoglobals // It's a read-only lookup table.
+var toolVersionsPluginNames = map[string]string{
+	"terragrunt": "terragrunt",
+	"terraform":  "terraform",
+	"opentofu":   "opentofu",
+	"tofu":       "opentofu",
+}
+
+// iacToolVersions holds the versions of the IaC tools resolved from the version files.
+// An empty version means that the tool isn't pinned.
+type iacToolVersions struct {
+	Terragrunt string
+	Terraform  string
+	OpenTofu   string
+}
+
+// WithIACToolsInstalledFromVersionFiles installs Terragrunt, Terraform and OpenTofu with the versions
+// pinned in the version files of the source directory.
+//
+// The supported files are .terragrunt-version, .terraform-version (tfenv), .opentofu-version (tofuenv)
+// and .tool-versions (asdf/mise). A dedicated version file takes precedence over .tool-versions, and
+// tools that aren't pinned in any file are installed with their default version.
+//
+// Parameters:
+// - ctx: The context to use when reading the version files.
+// - source: The source directory that includes the version files.
+//
+// Returns:
+// - *Terragrunt: The updated Terragrunt instance with the IaC tools installed.
+// - error: An error if a version file can't be read, or if it doesn't pin a concrete version.
+func (m *Terragrunt) WithIACToolsInstalledFromVersionFiles(
+	// ctx is the context to use when reading the version files.
+	// +optional
+	ctx context.Context,
+	// source is the source directory that includes the version files.
+	source *dagger.Directory,
+) (*Terragrunt, error) {
+	versions, err := resolveIACToolVersions(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.WithIACToolsInstalled(
+		handleToolVersions(versions.Terragrunt),
+		handleToolVersions(versions.Terraform),
+		handleToolVersions(versions.OpenTofu)), nil
+}
+
+// resolveIACToolVersions reads the version files at the root of the source directory, and returns
+// the versions of the IaC tools pinned in them.
+func resolveIACToolVersions(ctx context.Context, source *dagger.Directory) (iacToolVersions, error) {
+	var versions iacToolVersions
+
+	if source == nil {
+		return versions, nil
+	}
+
+	entries, err := source.Entries(ctx)
+	if err != nil {
+		return versions, WrapError(err, "failed to list the entries of the source directory")
+	}
+
+	contents := map[string]string{}
+
+	for _, entry := range entries {
+		switch entry {
+		case terraformVersionFile, terragruntVersionFile, openTofuVersionFile, toolVersionsFile:
+			content, err := source.File(entry).Contents(ctx)
+			if err != nil {
+				return versions, WrapErrorf(err, "failed to read the version file: %s", entry)
+			}
+
+			contents[entry] = content
+		}
+	}
+
+	if content, ok := contents[toolVersionsFile]; ok {
+		versions, err = parseToolVersionsFile(content)
+		if err != nil {
+			return versions, WrapErrorf(err, "failed to parse the version file: %s", toolVersionsFile)
+		}
+	}
+
+	for file, target := range map[string]*string{
+		terragruntVersionFile: &versions.Terragrunt,
+		terraformVersionFile:  &versions.Terraform,
+		openTofuVersionFile:   &versions.OpenTofu,
+	} {
+		content, ok := contents[file]
+		if !ok {
+			continue
+		}
+
+		version, err := parseVersionFile(content)
+		if err != nil {
+			return versions, WrapErrorf(err, "failed to parse the version file: %s", file)
+		}
+
+		if version != "" {
+			*target = version
+		}
+	}
+
+	return versions, nil
+}
+
+// parseVersionFile returns the version pinned in a single-tool version file (e.g., .terraform-version).
+// Empty lines and comments are ignored, and an empty version is returned if the file pins nothing.
+func parseVersionFile(content string) (string, error) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		return validatePinnedVersion(line)
+	}
+
+	return "", nil
+}
+
+// parseToolVersionsFile returns the versions of the IaC tools pinned in a .tool-versions file.
+// Each line is in the form "<plugin> <version> [<fallback versions>...]", and only the first
+// version is used. Plugins that aren't IaC tools are ignored.
+func parseToolVersionsFile(content string) (iacToolVersions, error) {
+	var versions iacToolVersions
+
+	for _, line := range strings.Split(content, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 { //nolint:mnd // A plugin name and at least one version.
+			continue
+		}
+
+		tool, ok := toolVersionsPluginNames[fields[0]]
+		if !ok {
+			continue
+		}
+
+		version, err := validatePinnedVersion(fields[1])
+		if err != nil {
+			return versions, WrapErrorf(err, "invalid version for %s", fields[0])
+		}
+
+		switch tool {
+		case "terragrunt":
+			versions.Terragrunt = version
+		case "terraform":
+			versions.Terraform = version
+		case "opentofu":
+			versions.OpenTofu = version
+		}
+	}
+
+	return versions, nil
+}
+
+// validatePinnedVersion returns an error if the version isn't a concrete version. Constraints such as
+// "latest", "latest:^1.5" or "min-required" can't be resolved without querying the releases.
+func validatePinnedVersion(version string) (string, error) {
+	if !pinnedVersionRegex.MatchString(version) {
+		return "", Errorf("the version %q isn't a concrete version, e.g., 1.9.7", version)
+	}
+
+	return version, nil
+}